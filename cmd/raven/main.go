@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/andyfusniak/raven-client-go/http"
 	"github.com/andyfusniak/raven-client-go/internal/cli"
@@ -28,42 +32,158 @@ func run() error {
 		endpoint = envEndpoint
 	}
 
-	ravenHTTPClient, err := http.NewClient(http.Config{
-		Endpoint: endpoint,
-		Timeout:  http.DefaultTimout,
-	})
-	if err != nil {
-		return err
-	}
-
 	appv := cli.NewApp(cli.Config{
-		Version:    version,
-		Endpoint:   endpoint,
-		GitCommit:  gitCommit,
-		HTTPClient: ravenHTTPClient,
+		Version:   version,
+		Endpoint:  endpoint,
+		GitCommit: gitCommit,
 	})
 
+	var flags clientFlags
 	root := cobra.Command{
 		Use:     "raven",
 		Short:   "raven is command line tool for managing Raven Mailer projects",
 		Version: version,
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
-			v := ctx.Value(cli.AppKey("app"))
-			_ = v.(*cli.App)
+			app := ctx.Value(cli.AppKey("app")).(*cli.App)
+
+			client, err := newHTTPClient(flags)
+			if err != nil {
+				return err
+			}
+			app.HTTPClient = client
+			return nil
 		},
 	}
+	root.PersistentFlags().StringVar(&flags.caCert, "cacert", os.Getenv("RAVEN_CACERT"),
+		"verify the server against the CA certificates in this PEM file")
+	root.PersistentFlags().StringVar(&flags.cert, "cert", os.Getenv("RAVEN_CERT"),
+		"client certificate PEM file for mutual TLS")
+	root.PersistentFlags().StringVar(&flags.key, "key", os.Getenv("RAVEN_KEY"),
+		"client private key PEM file for mutual TLS")
+	root.PersistentFlags().StringVar(&flags.proxy, "proxy", os.Getenv("RAVEN_PROXY"),
+		"send requests through this http, https or socks5 proxy URL")
+	root.PersistentFlags().StringVar(&flags.socket, "unix-socket", os.Getenv("RAVEN_UNIX_SOCKET"),
+		"connect to the API through this Unix domain socket")
+	debug, _ := strconv.ParseBool(os.Getenv("RAVEN_DEBUG"))
+	root.PersistentFlags().BoolVar(&flags.debug, "debug", debug,
+		"dump HTTP requests and responses to stderr with secrets redacted")
+
+	root.AddCommand(cli.NewCmdCancel())
 	root.AddCommand(cli.NewCmdCreate())
 	root.AddCommand(cli.NewCmdDelete())
+	root.AddCommand(cli.NewCmdExport())
 	root.AddCommand(cli.NewCmdGet())
 	root.AddCommand(cli.NewCmdList())
+	root.AddCommand(cli.NewCmdLogs())
+	root.AddCommand(cli.NewCmdResend())
+	root.AddCommand(cli.NewCmdRetry())
+	root.AddCommand(cli.NewCmdSend())
+	root.AddCommand(cli.NewCmdStats())
 	root.AddCommand(cli.NewCmdUpdate())
+	root.AddCommand(cli.NewCmdWebhook())
+	root.AddCommand(cli.NewCmdWait())
 	root.AddCommand(cli.NewCmdVersion(version, gitCommit, endpoint))
 
 	ctx := context.WithValue(context.Background(), cli.AppKey("app"), appv)
 	if err := root.ExecuteContext(ctx); err != nil {
 		fmt.Fprintf(os.Stderr, "%+v\n", err)
+		// the request ID lets the Raven team find the failed request
+		if id := meta.requestID(); id != "" && !strings.Contains(err.Error(), id) {
+			fmt.Fprintf(os.Stderr, "Request ID: %s\n", id)
+		}
 		os.Exit(1)
 	}
 	return nil
 }
+
+// clientFlags HTTP client settings from the command line or environment.
+type clientFlags struct {
+	caCert string
+	cert   string
+	key    string
+	proxy  string
+	socket string
+	debug  bool
+}
+
+// responseMeta remembers the request ID of a failed response for error
+// output and warns about deprecated API endpoints once each.
+type responseMeta struct {
+	mu       sync.Mutex
+	failedID string
+	warned   map[string]bool
+}
+
+var meta responseMeta
+
+func (m *responseMeta) record(rm http.ResponseMeta) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// a successful response clears the ID so that a later transport or
+	// validation error is not reported against an unrelated request
+	m.failedID = ""
+	if rm.StatusCode >= 400 {
+		m.failedID = rm.RequestID
+	}
+	var warnings []string
+	if rm.Deprecated {
+		w := "this API endpoint is deprecated, upgrade raven"
+		if !rm.Sunset.IsZero() {
+			w += fmt.Sprintf(" before %s", rm.Sunset.Format("2006-01-02"))
+		}
+		warnings = append(warnings, w)
+	}
+	warnings = append(warnings, rm.Warnings...)
+	for _, w := range warnings {
+		if m.warned[w] {
+			continue
+		}
+		if m.warned == nil {
+			m.warned = make(map[string]bool)
+		}
+		m.warned[w] = true
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+}
+
+func (m *responseMeta) requestID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.failedID
+}
+
+func newHTTPClient(f clientFlags) (*http.Client, error) {
+	opts := []http.Option{
+		http.WithTimeout(http.DefaultTimout),
+		http.WithUserAgent("raven/" + version),
+	}
+	if f.caCert != "" {
+		opts = append(opts, http.WithRootCAFile(f.caCert))
+	}
+	if f.cert != "" || f.key != "" {
+		if f.cert == "" || f.key == "" {
+			return nil, errors.New("--cert and --key must be used together")
+		}
+		opts = append(opts, http.WithClientCertFiles(f.cert, f.key))
+	}
+	if f.proxy != "" {
+		opts = append(opts, http.WithProxy(f.proxy))
+	}
+	if f.socket != "" {
+		opts = append(opts, http.WithUnixSocket(f.socket))
+	}
+	if f.debug {
+		opts = append(opts, http.WithDebug(os.Stderr))
+	}
+	opts = append(opts, http.WithResponseHook(meta.record))
+	// RAVEN_ENDPOINT may list fallback endpoints, e.g. one per region
+	endpoints := strings.Split(endpoint, ",")
+	for i := range endpoints {
+		endpoints[i] = strings.TrimSpace(endpoints[i])
+	}
+	if len(endpoints) > 1 {
+		opts = append(opts, http.WithFailover(endpoints[1:]...))
+	}
+	return http.NewClientWithOptions(endpoints[0], opts...)
+}
@@ -5,6 +5,7 @@ go 1.19
 require (
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v1.5.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,153 @@
+package http
+
+import (
+	"fmt"
+	"net/mail"
+	"net/textproto"
+	"strings"
+)
+
+// MaxRecipients is the largest combined number of To, Cc and Bcc
+// recipients accepted on a single mail.
+const MaxRecipients = 50
+
+// reservedHeaders are set by Raven Mailer and cannot be supplied as custom
+// headers.
+var reservedHeaders = map[string]struct{}{
+	"Bcc":                       {},
+	"Cc":                        {},
+	"Content-Transfer-Encoding": {},
+	"Content-Type":              {},
+	"Date":                      {},
+	"From":                      {},
+	"Message-Id":                {},
+	"Mime-Version":              {},
+	"Reply-To":                  {},
+	"Sender":                    {},
+	"Subject":                   {},
+	"To":                        {},
+}
+
+// Address an email address with an optional display name.
+type Address struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email"`
+}
+
+// String formats the address for use in a header, e.g.
+// "Jane Doe" <jane@example.com>.
+func (a Address) String() string {
+	if a.Name == "" {
+		return a.Email
+	}
+	return (&mail.Address{Name: a.Name, Address: a.Email}).String()
+}
+
+// ParseAddress parses a single RFC 5322 address such as
+// "Jane Doe <jane@example.com>".
+func ParseAddress(s string) (Address, error) {
+	a, err := mail.ParseAddress(s)
+	if err != nil {
+		return Address{}, &ValidationError{Field: "address", Value: s, Msg: err.Error()}
+	}
+	return Address{Name: a.Name, Email: a.Address}, nil
+}
+
+// ParseAddressList parses a comma separated list of RFC 5322 addresses.
+func ParseAddressList(s string) ([]Address, error) {
+	list, err := mail.ParseAddressList(s)
+	if err != nil {
+		return nil, &ValidationError{Field: "address", Value: s, Msg: err.Error()}
+	}
+	out := make([]Address, len(list))
+	for i, a := range list {
+		out[i] = Address{Name: a.Name, Email: a.Address}
+	}
+	return out, nil
+}
+
+// ValidationError is returned when parameters fail client-side validation.
+// No request is made.
+type ValidationError struct {
+	Field string
+	Value string
+	Msg   string
+}
+
+// Error string representation of a ValidationError.
+func (e *ValidationError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("invalid %s: %s", e.Field, e.Msg)
+	}
+	return fmt.Sprintf("invalid %s %q: %s", e.Field, e.Value, e.Msg)
+}
+
+// ValidateHeader checks that name is a well formed header field name that
+// Raven Mailer does not set itself, and that value cannot inject further
+// headers.
+func ValidateHeader(name, value string) error {
+	if name == "" {
+		return &ValidationError{Field: "header", Msg: "empty name"}
+	}
+	for _, r := range name {
+		// RFC 5322 field names are printable US-ASCII excluding colon
+		if r < 33 || r > 126 || r == ':' {
+			return &ValidationError{Field: "header", Value: name, Msg: "invalid character in name"}
+		}
+	}
+	if _, ok := reservedHeaders[textproto.CanonicalMIMEHeaderKey(name)]; ok {
+		return &ValidationError{Field: "header", Value: name, Msg: "reserved header"}
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return &ValidationError{Field: "header", Value: name, Msg: "value contains a line break"}
+	}
+	return nil
+}
+
+// validateRecipients checks every address in the send parameters and
+// returns the To list with EmailTo merged in.
+func validateRecipients(p *SendMailParams) ([]Address, error) {
+	to := p.To
+	if p.EmailTo != "" {
+		a, err := ParseAddress(p.EmailTo)
+		if err != nil {
+			return nil, err
+		}
+		to = append([]Address{a}, to...)
+	}
+	if len(to) == 0 {
+		return nil, &ValidationError{Field: "to", Msg: "at least one recipient is required"}
+	}
+	if n := len(to) + len(p.Cc) + len(p.Bcc); n > MaxRecipients {
+		return nil, &ValidationError{
+			Field: "recipients",
+			Msg:   fmt.Sprintf("%d recipients exceeds limit of %d", n, MaxRecipients),
+		}
+	}
+
+	fields := []struct {
+		name string
+		list []Address
+	}{{"to", to}, {"cc", p.Cc}, {"bcc", p.Bcc}}
+	for _, f := range fields {
+		field := f.name
+		for _, a := range f.list {
+			parsed, err := mail.ParseAddress(a.Email)
+			if err != nil {
+				return nil, &ValidationError{Field: field, Value: a.Email, Msg: err.Error()}
+			}
+			if parsed.Address != a.Email {
+				return nil, &ValidationError{Field: field, Value: a.Email, Msg: "not a bare email address"}
+			}
+			if strings.ContainsAny(a.Name, "\r\n") {
+				return nil, &ValidationError{Field: field, Value: a.Name, Msg: "name contains a line break"}
+			}
+		}
+	}
+	for name, value := range p.Headers {
+		if err := ValidateHeader(name, value); err != nil {
+			return nil, err
+		}
+	}
+	return to, nil
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestValidateHeader(t *testing.T) {
+	tests := []struct {
+		name, value string
+		msg         string // empty if valid
+	}{
+		{"X-Campaign", "spring", ""},
+		{"List-Unsubscribe", "<mailto:unsub@example.com>", ""},
+		{"X-Campaign", "", ""},
+		{"", "x", "empty name"},
+		{"X Campaign", "x", "invalid character in name"},
+		{"X-Campaign:", "x", "invalid character in name"},
+		{"X-Kampagne-ü", "x", "invalid character in name"},
+		{"subject", "x", "reserved header"},
+		{"BCC", "x", "reserved header"},
+		{"X-Campaign", "a\r\nBcc: victim@example.com", "value contains a line break"},
+		{"X-Campaign", "a\nb", "value contains a line break"},
+		{"X-Campaign", "a\rb", "value contains a line break"},
+	}
+	for _, tc := range tests {
+		err := ValidateHeader(tc.name, tc.value)
+		if tc.msg == "" {
+			if err != nil {
+				t.Errorf("ValidateHeader(%q, %q) = %v, want nil", tc.name, tc.value, err)
+			}
+			continue
+		}
+		if verr, ok := err.(*ValidationError); !ok || verr.Msg != tc.msg {
+			t.Errorf("ValidateHeader(%q, %q) = %v, want %q", tc.name, tc.value, err, tc.msg)
+		}
+	}
+}
+
+func TestValidateRecipients(t *testing.T) {
+	many := make([]Address, MaxRecipients)
+	for i := range many {
+		many[i] = Address{Email: fmt.Sprintf("r%d@example.com", i)}
+	}
+	tests := []struct {
+		name   string
+		params SendMailParams
+		field  string // empty if valid
+		msg    string
+	}{
+		{"email to", SendMailParams{EmailTo: "Ann <ann@example.com>"}, "", ""},
+		{"lists", SendMailParams{To: []Address{{Name: "Ann", Email: "ann@example.com"}},
+			Cc: []Address{{Email: "bob@example.com"}}, Bcc: []Address{{Email: "cat@example.com"}}}, "", ""},
+		{"at the recipient limit", SendMailParams{To: many}, "", ""},
+		{"over the recipient limit", SendMailParams{To: many, Bcc: []Address{{Email: "x@example.com"}}},
+			"recipients", "51 recipients exceeds limit of 50"},
+		{"no recipients", SendMailParams{}, "to", "at least one recipient is required"},
+		{"bad email to", SendMailParams{EmailTo: "not an address"}, "address", ""},
+		{"bad to", SendMailParams{To: []Address{{Email: "ann"}}}, "to", ""},
+		{"bad cc", SendMailParams{EmailTo: "ann@example.com", Cc: []Address{{Email: "bob@"}}}, "cc", ""},
+		{"bad bcc", SendMailParams{EmailTo: "ann@example.com", Bcc: []Address{{Email: "@example.com"}}}, "bcc", ""},
+		{"display name in email", SendMailParams{To: []Address{{Email: "Ann <ann@example.com>"}}},
+			"to", "not a bare email address"},
+		{"line break in email", SendMailParams{To: []Address{{Email: "ann@example.com\r\nBcc: x@example.com"}}},
+			"to", ""},
+		{"line break in name", SendMailParams{To: []Address{{Name: "Ann\r\nBcc: x@example.com", Email: "ann@example.com"}}},
+			"to", "name contains a line break"},
+		{"line break in cc name", SendMailParams{EmailTo: "ann@example.com",
+			Cc: []Address{{Name: "Bob\n", Email: "bob@example.com"}}}, "cc", "name contains a line break"},
+		{"line break in header", SendMailParams{EmailTo: "ann@example.com",
+			Headers: map[string]string{"X-Campaign": "a\r\nBcc: x@example.com"}}, "header", "value contains a line break"},
+		{"reserved header", SendMailParams{EmailTo: "ann@example.com",
+			Headers: map[string]string{"Reply-To": "x@example.com"}}, "header", "reserved header"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := tc.params
+			to, err := validateRecipients(&p)
+			if tc.field == "" {
+				if err != nil {
+					t.Fatalf("validateRecipients = %v", err)
+				}
+				if len(to) == 0 {
+					t.Error("no To recipients returned")
+				}
+				return
+			}
+			verr, ok := err.(*ValidationError)
+			if !ok || verr.Field != tc.field || (tc.msg != "" && verr.Msg != tc.msg) {
+				t.Errorf("validateRecipients error = %v, want field %s %q", err, tc.field, tc.msg)
+			}
+		})
+	}
+}
+
+func TestValidateRecipientsMergesEmailTo(t *testing.T) {
+	p := SendMailParams{EmailTo: "Ann <ann@example.com>", To: []Address{{Email: "bob@example.com"}}}
+	to, err := validateRecipients(&p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(to) != 2 || to[0] != (Address{Name: "Ann", Email: "ann@example.com"}) || to[1].Email != "bob@example.com" {
+		t.Errorf("To = %v", to)
+	}
+}
+
+func TestSendMailValidationMakesNoRequest(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+	_, err := c.SendMail(context.Background(), &SendMailParams{
+		ProjectID:  "p1",
+		TemplateID: "welcome",
+		EmailTo:    "ann@example.com",
+		Headers:    map[string]string{"X-Campaign": "a\nBcc: x@example.com"},
+	})
+	if _, ok := err.(*ValidationError); !ok {
+		t.Errorf("SendMail error = %v, want *ValidationError", err)
+	}
+	if !strings.Contains(fmt.Sprint(err), "line break") {
+		t.Errorf("SendMail error = %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// MaxAttachmentSize is the largest single attachment accepted.
+	MaxAttachmentSize = 10 << 20
+
+	// MaxTotalAttachmentSize is the largest combined size of all
+	// attachments and inline images on a single mail.
+	MaxTotalAttachmentSize = 25 << 20
+)
+
+// AttachmentError is returned by SendMail when an attachment is invalid or
+// exceeds the client-side size limits. No request is made.
+type AttachmentError struct {
+	Filename string
+	Size     int64 // bytes read so far, zero if the size was not the cause
+	Limit    int64
+	Msg      string
+}
+
+// Error string representation of an AttachmentError.
+func (e *AttachmentError) Error() string {
+	if e.Limit > 0 {
+		return fmt.Sprintf("attachment %q exceeds %d byte limit", e.Filename, e.Limit)
+	}
+	return fmt.Sprintf("attachment %q: %s", e.Filename, e.Msg)
+}
+
+// encodeAttachments reads and base64 encodes attachments and inline images,
+// enforcing MaxAttachmentSize and MaxTotalAttachmentSize.
+func encodeAttachments(attachments, inline []Attachment) ([]attachmentRequestBody, error) {
+	var total int64
+	var out []attachmentRequestBody
+
+	add := func(a Attachment, isInline bool) error {
+		if a.Filename == "" {
+			return &AttachmentError{Msg: "missing filename"}
+		}
+		if isInline && a.ContentID == "" {
+			return &AttachmentError{Filename: a.Filename, Msg: "inline attachment missing content id"}
+		}
+
+		content := a.Content
+		if content == nil {
+			if a.Reader == nil {
+				return &AttachmentError{Filename: a.Filename, Msg: "no content"}
+			}
+			b, err := io.ReadAll(io.LimitReader(a.Reader, MaxAttachmentSize+1))
+			if err != nil {
+				return errors.Wrapf(err, "read attachment %q", a.Filename)
+			}
+			content = b
+		}
+
+		size := int64(len(content))
+		if size > MaxAttachmentSize {
+			return &AttachmentError{Filename: a.Filename, Size: size, Limit: MaxAttachmentSize}
+		}
+		total += size
+		if total > MaxTotalAttachmentSize {
+			return &AttachmentError{Filename: a.Filename, Size: total, Limit: MaxTotalAttachmentSize}
+		}
+
+		ct := a.ContentType
+		if ct == "" {
+			ct = mime.TypeByExtension(strings.ToLower(filepath.Ext(a.Filename)))
+		}
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+
+		out = append(out, attachmentRequestBody{
+			Filename:    filepath.Base(a.Filename),
+			ContentType: ct,
+			Content:     base64.StdEncoding.EncodeToString(content),
+			ContentID:   a.ContentID,
+			Inline:      isInline,
+		})
+		return nil
+	}
+
+	for _, a := range attachments {
+		if err := add(a, false); err != nil {
+			return nil, err
+		}
+	}
+	for _, a := range inline {
+		if err := add(a, true); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
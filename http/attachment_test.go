@@ -0,0 +1,100 @@
+package http
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeAttachmentsLimits(t *testing.T) {
+	content := func(n int) []byte { return bytes.Repeat([]byte("x"), n) }
+	tests := []struct {
+		name      string
+		attach    []Attachment
+		inline    []Attachment
+		wantLimit int64 // zero if valid
+	}{
+		{"at the size limit", []Attachment{{Filename: "a.bin", Content: content(MaxAttachmentSize)}}, nil, 0},
+		{"over the size limit", []Attachment{{Filename: "a.bin", Content: content(MaxAttachmentSize + 1)}}, nil,
+			MaxAttachmentSize},
+		{"reader at the size limit", []Attachment{{Filename: "a.bin",
+			Reader: bytes.NewReader(content(MaxAttachmentSize))}}, nil, 0},
+		{"reader over the size limit", []Attachment{{Filename: "a.bin",
+			Reader: bytes.NewReader(content(MaxAttachmentSize + 1))}}, nil, MaxAttachmentSize},
+		{"at the total limit", []Attachment{
+			{Filename: "a.bin", Content: content(MaxAttachmentSize)},
+			{Filename: "b.bin", Content: content(MaxAttachmentSize)},
+		}, []Attachment{
+			{Filename: "c.png", ContentID: "c", Content: content(MaxTotalAttachmentSize - 2*MaxAttachmentSize)},
+		}, 0},
+		{"over the total limit", []Attachment{
+			{Filename: "a.bin", Content: content(MaxAttachmentSize)},
+			{Filename: "b.bin", Content: content(MaxAttachmentSize)},
+		}, []Attachment{
+			{Filename: "c.png", ContentID: "c", Content: content(MaxTotalAttachmentSize - 2*MaxAttachmentSize + 1)},
+		}, MaxTotalAttachmentSize},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := encodeAttachments(tc.attach, tc.inline)
+			if tc.wantLimit == 0 {
+				if err != nil {
+					t.Fatalf("encodeAttachments = %v", err)
+				}
+				if len(out) != len(tc.attach)+len(tc.inline) {
+					t.Errorf("encoded %d attachments, want %d", len(out), len(tc.attach)+len(tc.inline))
+				}
+				return
+			}
+			aerr, ok := err.(*AttachmentError)
+			if !ok || aerr.Limit != tc.wantLimit {
+				t.Fatalf("encodeAttachments error = %v, want limit %d", err, tc.wantLimit)
+			}
+			if aerr.Size <= tc.wantLimit {
+				t.Errorf("Size %d is within the limit %d", aerr.Size, tc.wantLimit)
+			}
+		})
+	}
+}
+
+func TestEncodeAttachments(t *testing.T) {
+	out, err := encodeAttachments(
+		[]Attachment{
+			{Filename: "dir/report.PDF", Content: []byte("%PDF")},
+			{Filename: "data", Content: []byte{0}},
+			{Filename: "notes.txt", ContentType: "text/markdown", Content: []byte("#")},
+		},
+		[]Attachment{{Filename: "logo.png", ContentID: "logo", Content: []byte("png")}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []attachmentRequestBody{
+		{Filename: "report.PDF", ContentType: "application/pdf", Content: "JVBERg=="},
+		{Filename: "data", ContentType: "application/octet-stream", Content: "AA=="},
+		{Filename: "notes.txt", ContentType: "text/markdown", Content: "Iw=="},
+		{Filename: "logo.png", ContentType: "image/png", Content: "cG5n", ContentID: "logo", Inline: true},
+	}
+	for i := range want {
+		if out[i] != want[i] {
+			t.Errorf("attachment %d = %+v, want %+v", i, out[i], want[i])
+		}
+	}
+
+	invalid := []struct {
+		name   string
+		attach []Attachment
+		inline []Attachment
+		msg    string
+	}{
+		{"missing filename", []Attachment{{Content: []byte("x")}}, nil, "missing filename"},
+		{"no content", []Attachment{{Filename: "a.txt"}}, nil, "no content"},
+		{"inline without content id", nil, []Attachment{{Filename: "a.png", Content: []byte("x")}},
+			"inline attachment missing content id"},
+	}
+	for _, tc := range invalid {
+		_, err := encodeAttachments(tc.attach, tc.inline)
+		if aerr, ok := err.(*AttachmentError); !ok || aerr.Msg != tc.msg {
+			t.Errorf("%s: error = %v, want %q", tc.name, err, tc.msg)
+		}
+	}
+}
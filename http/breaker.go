@@ -0,0 +1,281 @@
+package http
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrCircuitOpen is returned without contacting the API while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState represents the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed requests flow normally.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen requests fail fast with ErrCircuitOpen.
+	BreakerOpen
+
+	// BreakerHalfOpen a limited number of probe requests are let through.
+	BreakerHalfOpen
+)
+
+// String representation of a BreakerState.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+const (
+	defaultBreakerConsecutiveFailures = 5
+	defaultBreakerMinRequests         = 10
+	defaultBreakerWindow              = 60 * time.Second
+	defaultBreakerOpenTimeout         = 30 * time.Second
+	defaultBreakerHalfOpenMaxRequests = 1
+)
+
+// BreakerConfig parameters to configure a new circuit breaker.
+type BreakerConfig struct {
+	// ConsecutiveFailures opens the breaker after this many failed requests
+	// in a row. If both ConsecutiveFailures and FailureRatio are left
+	// unset defaults to 5.
+	ConsecutiveFailures int
+
+	// FailureRatio opens the breaker when the proportion of failed requests
+	// within Window reaches this value (0 < ratio <= 1). Left unset the
+	// ratio check is disabled.
+	FailureRatio float64
+
+	// MinRequests is the number of requests that must be seen within Window
+	// before FailureRatio is considered. Left unset defaults to 10.
+	MinRequests int
+
+	// Window is the interval after which the counts used by FailureRatio
+	// are reset while the breaker is closed. Left unset defaults to 60s.
+	Window time.Duration
+
+	// OpenTimeout is how long the breaker stays open before moving to
+	// half-open. Left unset defaults to 30s.
+	OpenTimeout time.Duration
+
+	// HalfOpenMaxRequests is the number of probe requests allowed while
+	// half-open. If they all succeed the breaker closes. Left unset
+	// defaults to 1.
+	HalfOpenMaxRequests int
+
+	// OnStateChange is called after every state transition. It is called
+	// synchronously from the goroutine making the request so it should
+	// not block.
+	OnStateChange func(from, to BreakerState)
+}
+
+// CircuitBreaker guards the Raven Mailer API. Transport errors and 5xx
+// responses count as failures; all other responses count as successes.
+type CircuitBreaker struct {
+	config BreakerConfig
+
+	mu          sync.Mutex
+	state       BreakerState
+	expiry      time.Time // end of the current window (closed) or timeout (open)
+	requests    int
+	failures    int
+	consecutive int
+	inFlight    int // probe requests in progress while half-open
+	successes   int // successful probes while half-open
+
+	// generation is incremented on every state change so that outcomes
+	// of requests let through in an earlier state are ignored
+	generation uint64
+
+	now func() time.Time
+}
+
+// NewCircuitBreaker creates a new circuit breaker in the closed state.
+func NewCircuitBreaker(c BreakerConfig) *CircuitBreaker {
+	if c.ConsecutiveFailures <= 0 && c.FailureRatio <= 0 {
+		c.ConsecutiveFailures = defaultBreakerConsecutiveFailures
+	}
+	if c.MinRequests <= 0 {
+		c.MinRequests = defaultBreakerMinRequests
+	}
+	if c.Window <= 0 {
+		c.Window = defaultBreakerWindow
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = defaultBreakerOpenTimeout
+	}
+	if c.HalfOpenMaxRequests <= 0 {
+		c.HalfOpenMaxRequests = defaultBreakerHalfOpenMaxRequests
+	}
+
+	b := &CircuitBreaker{
+		config: c,
+		state:  BreakerClosed,
+		now:    time.Now,
+	}
+	b.expiry = b.now().Add(c.Window)
+	return b
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	from, to := b.refresh(b.now())
+	b.mu.Unlock()
+
+	b.notify(from, to)
+	return to
+}
+
+// allow reports whether a request may proceed. It returns ErrCircuitOpen
+// if the breaker is open or the half-open probe limit has been reached.
+// Otherwise it returns the generation to pass to record or release.
+func (b *CircuitBreaker) allow() (uint64, error) {
+	b.mu.Lock()
+	from, to := b.refresh(b.now())
+	defer b.notify(from, to)
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		return 0, ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.inFlight >= b.config.HalfOpenMaxRequests {
+			return 0, ErrCircuitOpen
+		}
+		b.inFlight++
+	}
+	return b.generation, nil
+}
+
+// record reports the outcome of a request previously let through by allow
+// in generation gen. Outcomes from an earlier generation are ignored, so a
+// slow request admitted while closed cannot count as a half-open probe.
+func (b *CircuitBreaker) record(gen uint64, success bool) {
+	b.mu.Lock()
+	now := b.now()
+	from, _ := b.refresh(now)
+	if gen != b.generation {
+		to := b.state
+		b.mu.Unlock()
+		b.notify(from, to)
+		return
+	}
+
+	switch b.state {
+	case BreakerClosed:
+		b.requests++
+		if success {
+			b.consecutive = 0
+		} else {
+			b.failures++
+			b.consecutive++
+			if b.tripped() {
+				b.setState(BreakerOpen, now)
+			}
+		}
+	case BreakerHalfOpen:
+		if b.inFlight > 0 {
+			b.inFlight--
+		}
+		if !success {
+			b.setState(BreakerOpen, now)
+			break
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenMaxRequests {
+			b.setState(BreakerClosed, now)
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+// release gives back a half-open probe slot taken by allow in generation
+// gen without recording an outcome, used when the caller cancels the
+// request.
+func (b *CircuitBreaker) release(gen uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if gen == b.generation && b.state == BreakerHalfOpen && b.inFlight > 0 {
+		b.inFlight--
+	}
+}
+
+// tripped reports whether the failure counts warrant opening the breaker.
+// The caller must hold b.mu.
+func (b *CircuitBreaker) tripped() bool {
+	if b.config.ConsecutiveFailures > 0 && b.consecutive >= b.config.ConsecutiveFailures {
+		return true
+	}
+	if b.config.FailureRatio > 0 && b.requests >= b.config.MinRequests {
+		return float64(b.failures)/float64(b.requests) >= b.config.FailureRatio
+	}
+	return false
+}
+
+// refresh applies any time based transitions, resetting the closed window
+// or moving from open to half-open once the timeout has elapsed. It returns
+// the state before and after. The caller must hold b.mu.
+func (b *CircuitBreaker) refresh(now time.Time) (from, to BreakerState) {
+	from = b.state
+	switch b.state {
+	case BreakerClosed:
+		if now.After(b.expiry) {
+			b.resetCounts()
+			b.expiry = now.Add(b.config.Window)
+		}
+	case BreakerOpen:
+		if now.After(b.expiry) {
+			b.setState(BreakerHalfOpen, now)
+		}
+	}
+	return from, b.state
+}
+
+// setState moves the breaker to a new state and resets all counts. The
+// caller must hold b.mu.
+func (b *CircuitBreaker) setState(s BreakerState, now time.Time) {
+	b.state = s
+	b.generation++
+	b.resetCounts()
+	switch s {
+	case BreakerClosed:
+		b.expiry = now.Add(b.config.Window)
+	case BreakerOpen:
+		b.expiry = now.Add(b.config.OpenTimeout)
+	case BreakerHalfOpen:
+		b.expiry = time.Time{}
+	}
+}
+
+func (b *CircuitBreaker) resetCounts() {
+	b.requests = 0
+	b.failures = 0
+	b.consecutive = 0
+	b.inFlight = 0
+	b.successes = 0
+}
+
+// notify calls the OnStateChange callback if the state has changed. It
+// must be called without holding b.mu.
+func (b *CircuitBreaker) notify(from, to BreakerState) {
+	if from != to && b.config.OnStateChange != nil {
+		b.config.OnStateChange(from, to)
+	}
+}
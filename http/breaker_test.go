@@ -0,0 +1,159 @@
+package http
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestBreaker returns a breaker driven by the returned clock, which is
+// advanced by assigning to it, and the transitions it has reported.
+func newTestBreaker(c BreakerConfig) (*CircuitBreaker, *time.Time, *[]string) {
+	now := time.Unix(1700000000, 0)
+	var changes []string
+	c.OnStateChange = func(from, to BreakerState) {
+		changes = append(changes, from.String()+"->"+to.String())
+	}
+	b := NewCircuitBreaker(c)
+	b.now = func() time.Time { return now }
+	b.expiry = now.Add(b.config.Window)
+	return b, &now, &changes
+}
+
+func mustAllow(t *testing.T, b *CircuitBreaker) uint64 {
+	t.Helper()
+	gen, err := b.allow()
+	if err != nil {
+		t.Fatalf("allow in state %s: %v", b.State(), err)
+	}
+	return gen
+}
+
+func TestBreakerTransitions(t *testing.T) {
+	b, now, changes := newTestBreaker(BreakerConfig{
+		ConsecutiveFailures: 2,
+		OpenTimeout:         10 * time.Second,
+		HalfOpenMaxRequests: 2,
+	})
+
+	b.record(mustAllow(t, b), false)
+	b.record(mustAllow(t, b), true)
+	b.record(mustAllow(t, b), false)
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("state %s after non-consecutive failures, want closed", s)
+	}
+	b.record(mustAllow(t, b), false)
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("state %s after 2 consecutive failures, want open", s)
+	}
+	if _, err := b.allow(); err != ErrCircuitOpen {
+		t.Fatalf("allow while open = %v, want ErrCircuitOpen", err)
+	}
+
+	*now = now.Add(10*time.Second + time.Millisecond)
+	p1 := mustAllow(t, b)
+	p2 := mustAllow(t, b)
+	if _, err := b.allow(); err != ErrCircuitOpen {
+		t.Fatalf("allow beyond the probe limit = %v, want ErrCircuitOpen", err)
+	}
+	b.record(p1, true)
+	if s := b.State(); s != BreakerHalfOpen {
+		t.Fatalf("state %s after 1 of 2 probes, want half-open", s)
+	}
+	b.record(p2, true)
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("state %s after all probes succeeded, want closed", s)
+	}
+
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if !reflect.DeepEqual(*changes, want) {
+		t.Errorf("transitions %v, want %v", *changes, want)
+	}
+}
+
+func TestBreakerProbeFailureReopens(t *testing.T) {
+	b, now, changes := newTestBreaker(BreakerConfig{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+
+	b.record(mustAllow(t, b), false)
+	*now = now.Add(2 * time.Second)
+	b.record(mustAllow(t, b), false)
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("state %s after a failed probe, want open", s)
+	}
+	want := []string{"closed->open", "open->half-open", "half-open->open"}
+	if !reflect.DeepEqual(*changes, want) {
+		t.Errorf("transitions %v, want %v", *changes, want)
+	}
+}
+
+func TestBreakerIgnoresStaleOutcomes(t *testing.T) {
+	b, now, changes := newTestBreaker(BreakerConfig{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+
+	// a slow request admitted while closed finishes after the breaker has
+	// opened and moved to half-open
+	slow := mustAllow(t, b)
+	b.record(mustAllow(t, b), false)
+	*now = now.Add(2 * time.Second)
+	b.record(slow, true)
+
+	if s := b.State(); s != BreakerHalfOpen {
+		t.Fatalf("state %s after a stale success, want half-open", s)
+	}
+	want := []string{"closed->open", "open->half-open"}
+	if !reflect.DeepEqual(*changes, want) {
+		t.Errorf("transitions %v, want %v", *changes, want)
+	}
+
+	// the probe slot is still free and a stale release does not free more
+	probe := mustAllow(t, b)
+	b.release(slow)
+	if _, err := b.allow(); err != ErrCircuitOpen {
+		t.Fatalf("allow after a stale release = %v, want ErrCircuitOpen", err)
+	}
+	b.record(slow, false)
+	if s := b.State(); s != BreakerHalfOpen {
+		t.Fatalf("state %s after a stale failure, want half-open", s)
+	}
+	b.record(probe, true)
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("state %s after the probe succeeded, want closed", s)
+	}
+}
+
+func TestBreakerRelease(t *testing.T) {
+	b, now, _ := newTestBreaker(BreakerConfig{ConsecutiveFailures: 1, OpenTimeout: time.Second})
+
+	b.record(mustAllow(t, b), false)
+	*now = now.Add(2 * time.Second)
+	b.release(mustAllow(t, b))
+	gen := mustAllow(t, b)
+	b.record(gen, true)
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("state %s, want closed", s)
+	}
+}
+
+func TestBreakerFailureRatio(t *testing.T) {
+	b, now, _ := newTestBreaker(BreakerConfig{
+		FailureRatio: 0.5,
+		MinRequests:  4,
+		Window:       time.Minute,
+	})
+
+	// the counts are reset when the window ends
+	b.record(mustAllow(t, b), false)
+	b.record(mustAllow(t, b), false)
+	b.record(mustAllow(t, b), true)
+	*now = now.Add(time.Minute + time.Second)
+	b.record(mustAllow(t, b), false)
+	if s := b.State(); s != BreakerClosed {
+		t.Fatalf("state %s with counts from an old window, want closed", s)
+	}
+
+	b.record(mustAllow(t, b), true)
+	b.record(mustAllow(t, b), true)
+	b.record(mustAllow(t, b), false)
+	if s := b.State(); s != BreakerOpen {
+		t.Fatalf("state %s at a 50%% failure ratio, want open", s)
+	}
+}
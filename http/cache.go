@@ -0,0 +1,140 @@
+package http
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultCacheSize is the number of responses held by an LRUCache created
+// with a size of zero.
+const DefaultCacheSize = 256
+
+// CacheEntry a cached response body and the ETag the server sent with it.
+type CacheEntry struct {
+	ETag string
+	Body []byte
+}
+
+// Cache stores GET response bodies so they can be revalidated with
+// If-None-Match. Keys combine the request URL with a hash of the
+// credentials sent, so a cache shared by clients authenticating as
+// different users never serves one user's response to another.
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+}
+
+// LRUCache is an in-memory Cache that evicts the least recently used entry
+// once full.
+type LRUCache struct {
+	size int
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+// NewLRUCache creates a new in-memory cache holding up to size entries.
+// A size of zero or less uses DefaultCacheSize.
+func NewLRUCache(size int) *LRUCache {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &LRUCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get returns the entry stored under key, marking it as recently used.
+func (c *LRUCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*lruItem).entry, true
+}
+
+// Set stores entry under key, evicting the least recently used entry if
+// the cache is full.
+func (c *LRUCache) Set(key string, entry *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*lruItem).entry = entry
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&lruItem{key: key, entry: entry})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+// cacheKey returns the cache key for a GET of uri: the URL, prefixed with a
+// hash of the Authorization header if the client sends one.
+func (c *Client) cacheKey(uri string) string {
+	req := &http.Request{Header: make(http.Header)}
+	c.setHeaders(req)
+	cred := req.Header.Get("Authorization")
+	if cred == "" {
+		return uri
+	}
+	sum := sha256.Sum256([]byte(cred))
+	return hex.EncodeToString(sum[:16]) + " " + uri
+}
+
+// cacheResponse serves the cached body for a 304 Not Modified response and
+// stores the body of a 200 OK response that carries an ETag. The entry is
+// the one used to set If-None-Match on the request, or nil.
+func (c *Client) cacheResponse(key string, entry *CacheEntry, res *http.Response) (*http.Response, error) {
+	switch {
+	case res.StatusCode == http.StatusNotModified && entry != nil:
+		res.Body.Close()
+		res.StatusCode = http.StatusOK
+		res.Status = "200 OK"
+		res.ContentLength = int64(len(entry.Body))
+		res.Body = io.NopCloser(bytes.NewReader(entry.Body))
+	case res.StatusCode == http.StatusOK && res.Header.Get("ETag") != "":
+		body, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "read response body")
+		}
+		c.cache.Set(key, &CacheEntry{
+			ETag: res.Header.Get("ETag"),
+			Body: body,
+		})
+		res.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return res, nil
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLRUCacheEviction(t *testing.T) {
+	c := NewLRUCache(2)
+	c.Set("a", &CacheEntry{ETag: `"a"`})
+	c.Set("b", &CacheEntry{ETag: `"b"`})
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("a missing")
+	}
+	// b is now the least recently used
+	c.Set("c", &CacheEntry{ETag: `"c"`})
+	if _, ok := c.Get("b"); ok {
+		t.Error("b not evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.Get(k); !ok {
+			t.Errorf("%s evicted", k)
+		}
+	}
+	c.Set("a", &CacheEntry{ETag: `"a2"`})
+	if e, _ := c.Get("a"); e.ETag != `"a2"` {
+		t.Errorf("a ETag %s after update, want \"a2\"", e.ETag)
+	}
+	if n := c.Len(); n != 2 {
+		t.Errorf("Len = %d, want 2", n)
+	}
+	if n := NewLRUCache(0).size; n != DefaultCacheSize {
+		t.Errorf("default size %d, want %d", n, DefaultCacheSize)
+	}
+}
+
+const cachedMail = `{"data":{"id":"m1","projectId":"p1","status":"delivered"}}`
+
+func TestCacheRevalidation(t *testing.T) {
+	var conditional []string
+	cache := NewLRUCache(0)
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		inm := r.Header.Get("If-None-Match")
+		conditional = append(conditional, inm)
+		w.Header().Set("ETag", `"v1"`)
+		if inm == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, cachedMail)
+	}, WithCache(cache), WithBearerToken("token-a"))
+
+	for i := 0; i < 2; i++ {
+		m, err := c.GetMail(context.Background(), "p1", "m1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.ID != "m1" {
+			t.Errorf("request %d: mail %+v", i, m)
+		}
+	}
+	if fmt.Sprint(conditional) != `[ "v1"]` {
+		t.Errorf("If-None-Match headers %q, want none then \"v1\"", conditional)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("cache holds %d entries, want 1", cache.Len())
+	}
+}
+
+func TestCacheKeyedByCredentials(t *testing.T) {
+	var conditional []string
+	h := func(w http.ResponseWriter, r *http.Request) {
+		conditional = append(conditional, r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", `"`+r.Header.Get("Authorization")+`"`)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, cachedMail)
+	}
+	cache := NewLRUCache(0)
+	a := newTestClient(t, h, WithCache(cache), WithBearerToken("token-a"))
+	b := newTestClient(t, h, WithCache(cache), WithBearerToken("token-b"))
+	for _, c := range []*Client{a, b} {
+		if _, err := c.GetMail(context.Background(), "p1", "m1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fmt.Sprint(conditional) != "[ ]" {
+		t.Errorf("If-None-Match headers %q, want none", conditional)
+	}
+	for _, c := range []*Client{a, b} {
+		key := c.cacheKey(c.buildURL("projects/p1/mail/m1", nil).String())
+		if strings.Contains(key, "token") {
+			t.Errorf("cache key %q contains the credentials", key)
+		}
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("no cache entry for %q", key)
+		}
+	}
+}
+
+func TestNotModifiedWithoutEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		always  bool // the server answers 304 even to no-cache
+		wantErr bool
+	}{
+		{"refetched", false, false},
+		{"always not modified", true, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if r.Header.Get("If-None-Match") != "" {
+					t.Errorf("If-None-Match sent without a cached entry")
+				}
+				if tc.always || r.Header.Get("Cache-Control") != "no-cache" {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, cachedMail)
+			}, WithCache(NewLRUCache(0)))
+
+			m, err := c.GetMail(context.Background(), "p1", "m1")
+			if requests != 2 {
+				t.Errorf("sent %d requests, want 2", requests)
+			}
+			if tc.wantErr {
+				if err == nil || m != nil {
+					t.Errorf("GetMail = %+v, %v, want an error", m, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.ID != "m1" {
+				t.Errorf("mail %+v", m)
+			}
+		})
+	}
+}
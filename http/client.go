@@ -8,7 +8,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
@@ -20,56 +21,212 @@ var DefaultTimout = time.Duration(10 * time.Second)
 // Client to communicate with Raven Mailer API
 type Client struct {
 	endpoint *url.URL
+	pool     *endpointPool
 	client   *http.Client
+	breaker  *CircuitBreaker
+	cache    Cache
+	strict   bool
+
+	userAgent    string
+	projectID    string
+	auth         func(*http.Request)
+	headers      http.Header
+	retries      int
+	retryBackoff time.Duration
+	logger       Logger
+	responseHook func(ResponseMeta)
 }
 
-// Config parameters to configure a new HTTP client.
+// Config parameters to configure a new HTTP client. See NewClient. New
+// code should prefer NewClientWithOptions.
 type Config struct {
 	// Endpoint e.g. https://api.ravenmailer.com/v1
 	Endpoint string
 
-	// Timeout in seconds for request. Left unset defaults to
+	// Endpoints (optional) fallback endpoints in order of preference, e.g.
+	// one per region. If Endpoint is empty the first is the primary. See
+	// WithFailover.
+	Endpoints []string
+
+	// EndpointCooldown how long a failed endpoint is skipped. Left unset
+	// defaults to DefaultEndpointCooldown.
+	EndpointCooldown time.Duration
+
+	// Timeout in seconds for request. Left unset defaults to DefaultTimout.
 	Timeout time.Duration
+
+	// CircuitBreaker (optional) fails requests fast with ErrCircuitOpen
+	// while the API is unavailable. Left unset no breaker is used.
+	CircuitBreaker *CircuitBreaker
+
+	// Cache (optional) stores GET responses that carry an ETag. Subsequent
+	// requests send If-None-Match and a 304 Not Modified is served from
+	// the cache. See NewLRUCache.
+	Cache Cache
+
+	// StrictDecoding rejects responses containing fields unknown to this
+	// version of the client with a *SchemaDriftError. Intended for
+	// contract tests; by default unknown fields are ignored so that new
+	// server fields do not break deployed clients.
+	StrictDecoding bool
+
+	// CACertFile (optional) PEM file of CA certificates used to verify the
+	// server instead of the system roots. See WithRootCAFile.
+	CACertFile string
+
+	// CertFile and KeyFile (optional) PEM files of the client certificate
+	// and private key for mutual TLS. Both or neither must be set.
+	CertFile string
+	KeyFile  string
+
+	// MinTLSVersion (optional) e.g. tls.VersionTLS13.
+	MinTLSVersion uint16
+
+	// Proxy (optional) http, https or socks5 proxy URL.
+	Proxy string
+
+	// UnixSocket (optional) path of a Unix domain socket to connect to
+	// instead of the endpoint's host. See WithUnixSocket.
+	UnixSocket string
 }
 
-// NewClient creates a new Raven Mailer HTTP client.
+// NewClient creates a new Raven Mailer HTTP client from a Config.
 func NewClient(c Config) (*Client, error) {
-	tr := &http.Transport{
-		MaxIdleConnsPerHost: 10,
-	}
-	client := &http.Client{
-		Transport: tr,
-		Timeout:   c.Timeout,
+	endpoint := c.Endpoint
+	if endpoint == "" && len(c.Endpoints) > 0 {
+		endpoint, c.Endpoints = c.Endpoints[0], c.Endpoints[1:]
 	}
+	return NewClientWithOptions(endpoint, WithConfig(c))
+}
 
-	_, err := url.Parse(c.Endpoint)
+// NewClientWithOptions creates a new Raven Mailer HTTP client for
+// endpoint, e.g. https://api.ravenmailer.com/v1. An invalid endpoint or
+// option is returned as an error.
+func NewClientWithOptions(endpoint string, opts ...Option) (*Client, error) {
+	u, err := parseEndpoint(endpoint)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%+v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
 
-	u, err := url.Parse(c.Endpoint)
-	if err != nil {
-		return nil, errors.Wrap(err, "url parse")
+	o := options{
+		timeout:   DefaultTimout,
+		userAgent: DefaultUserAgent,
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		if err := opt(&o); err != nil {
+			return nil, errors.Wrap(err, "new client")
+		}
+	}
+
+	client := o.httpClient
+	switch {
+	case client != nil && o.transport != nil:
+		return nil, errors.New("new client: WithHTTPClient and WithTransport cannot be combined")
+	case client != nil:
+		if o.timeoutSet || o.transportOptionsSet() {
+			hc := *client
+			if o.timeoutSet {
+				hc.Timeout = o.timeout
+			}
+			rt := hc.Transport
+			if rt == nil {
+				rt = http.DefaultTransport
+			}
+			if hc.Transport, err = o.buildTransport(rt); err != nil {
+				return nil, errors.Wrap(err, "new client")
+			}
+			client = &hc
+		}
+	default:
+		tr, err := o.buildTransport(o.transport)
+		if err != nil {
+			return nil, errors.Wrap(err, "new client")
+		}
+		client = &http.Client{
+			Transport: tr,
+			Timeout:   o.timeout,
+		}
+	}
+
+	if o.debug != nil {
+		hc := *client
+		rt := hc.Transport
+		if rt == nil {
+			rt = http.DefaultTransport
+		}
+		hc.Transport = &debugTransport{next: rt, w: o.debug}
+		client = &hc
 	}
 
 	return &Client{
-		endpoint: u,
-		client:   client,
+		endpoint:     u,
+		pool:         newEndpointPool(append([]*url.URL{u}, o.failover...), o.cooldown),
+		client:       client,
+		breaker:      o.breaker,
+		cache:        o.cache,
+		strict:       o.strict,
+		userAgent:    o.userAgent,
+		projectID:    o.projectID,
+		auth:         o.auth,
+		headers:      o.headers,
+		retries:      o.retries,
+		retryBackoff: o.retryBackoff,
+		logger:       o.logger,
+		responseHook: o.responseHook,
 	}, nil
 }
 
+// project returns projectID, or the default project if projectID is empty.
+func (c *Client) project(projectID string) string {
+	if projectID == "" {
+		return c.projectID
+	}
+	return projectID
+}
+
+// buildURL resolves path, which must already be escaped (see pathf),
+// against the endpoint. The endpoint's base path is kept, so an endpoint of
+// https://api.ravenmailer.com/v1 and a path of projects/p1 give
+// https://api.ravenmailer.com/v1/projects/p1.
 func (c *Client) buildURL(path string, query url.Values) *url.URL {
-	uri := url.URL{
-		Scheme:     c.endpoint.Scheme,
-		Host:       fmt.Sprintf("%s:%s", c.endpoint.Hostname(), c.endpoint.Port()),
-		Path:       c.endpoint.Path + "/" + path,
-		ForceQuery: false,
+	base := *c.endpoint
+	base.RawQuery = ""
+	base.Fragment = ""
+	if p := base.EscapedPath(); !strings.HasSuffix(p, "/") {
+		base.RawPath = p + "/"
+		base.Path = base.Path + "/"
 	}
+
+	ref := &url.URL{RawPath: path}
+	ref.Path, _ = url.PathUnescape(path)
+	uri := base.ResolveReference(ref)
 	if query != nil {
 		uri.RawQuery = query.Encode()
 	}
-	return &uri
+	return uri
+}
+
+// pathf formats a relative request path, escaping each argument as a
+// single path segment. Arguments of "." and ".." are escaped so that they
+// are not removed when the path is resolved. An empty argument is rejected
+// with a *ValidationError, as it would address a different resource, e.g.
+// the mail list instead of a single mail entry.
+func pathf(format string, args ...string) (string, error) {
+	v := make([]interface{}, len(args))
+	for i, a := range args {
+		switch a {
+		case "":
+			return "", &ValidationError{Field: "id", Msg: "must not be empty"}
+		case ".", "..":
+			v[i] = strings.Repeat("%2E", len(a))
+		default:
+			v[i] = url.PathEscape(a)
+		}
+	}
+	return fmt.Sprintf(format, v...), nil
 }
 
 // ListProjects fetches a slice of projects for a given user.
@@ -79,9 +236,9 @@ func (c *Client) ListProjects(ctx context.Context, userID string) ([]Project, er
 		"userId": []string{userID},
 	}
 	uri := c.buildURL("projects", query)
-	res, err := c.request(http.MethodGet, uri.String(), nil)
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "http get request failed")
+		return nil, requestError(err, "http get request failed")
 	}
 	defer res.Body.Close()
 
@@ -89,9 +246,7 @@ func (c *Client) ListProjects(ctx context.Context, userID string) ([]Project, er
 	var container struct {
 		Data []Project `json:"data"`
 	}
-	dec := json.NewDecoder(res.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&container); err != nil {
+	if err := c.decode(res.Body, &container); err != nil {
 		return nil, errors.Wrapf(err, "json decode list projects")
 	}
 	return container.Data, nil
@@ -100,21 +255,21 @@ func (c *Client) ListProjects(ctx context.Context, userID string) ([]Project, er
 // ListTransports fetches a slice of transports for the current project.
 func (c *Client) ListTransports(ctx context.Context, projectID string) ([]Transport, error) {
 	// build the URL including query params
-	path := fmt.Sprintf("projects/%s/transports", projectID)
+	path, err := pathf("projects/%s/transports", c.project(projectID))
+	if err != nil {
+		return nil, err
+	}
 	uri := c.buildURL(path, nil)
-	fmt.Printf("%s\n", uri)
-	res, err := c.request(http.MethodGet, uri.String(), nil)
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "http get request failed")
+		return nil, requestError(err, "http get request failed")
 	}
 	defer res.Body.Close()
 
 	var container struct {
 		Data []Transport `json:"data"`
 	}
-	dec := json.NewDecoder(res.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&container); err != nil {
+	if err := c.decode(res.Body, &container); err != nil {
 		return nil, errors.Wrapf(err, "json decode list transports")
 	}
 	return container.Data, nil
@@ -129,7 +284,7 @@ func (c *Client) CreateGroup(ctx context.Context, projectID, name string) (*Grou
 
 	// request body
 	req := createGroupRequest{
-		ProjectID: projectID,
+		ProjectID: c.project(projectID),
 		Name:      name,
 	}
 	body := new(bytes.Buffer)
@@ -138,46 +293,40 @@ func (c *Client) CreateGroup(ctx context.Context, projectID, name string) (*Grou
 	}
 
 	// do request
-	path := fmt.Sprintf("projects/%s/groups", projectID)
+	path, err := pathf("projects/%s/groups", c.project(projectID))
+	if err != nil {
+		return nil, err
+	}
 	uri := c.buildURL(path, nil)
-	res, err := c.request(http.MethodPost, uri.String(), body)
+	res, err := c.request(ctx, http.MethodPost, uri.String(), body)
 	if err != nil {
-		return nil, errors.Wrap(err, "http post request failed")
+		return nil, requestError(err, "http post request failed")
 	}
 	defer res.Body.Close()
 
-	// 4xx range
-	if res.StatusCode >= 400 && res.StatusCode < 500 {
-		return nil, decodeAPIError(res.Body)
-	}
-
 	// decode response
-	return decodeGroupResponse(res.Body)
+	return c.decodeGroupResponse(res.Body)
 }
 
 // ListGroups fetches a slice of groups for the current project.
 func (c *Client) ListGroups(ctx context.Context, projectID string) ([]Group, error) {
 	// build the URL including query params
-	path := fmt.Sprintf("projects/%s/groups", projectID)
+	path, err := pathf("projects/%s/groups", c.project(projectID))
+	if err != nil {
+		return nil, err
+	}
 	uri := c.buildURL(path, nil)
-	res, err := c.request(http.MethodGet, uri.String(), nil)
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "http get request failed")
+		return nil, requestError(err, "http get request failed")
 	}
 	defer res.Body.Close()
 
-	// 4xx range
-	if res.StatusCode >= 400 && res.StatusCode < 500 {
-		return nil, decodeAPIError(res.Body)
-	}
-
 	// json decode
 	var container struct {
 		Data []Group `json:"data"`
 	}
-	dec := json.NewDecoder(res.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&container); err != nil {
+	if err := c.decode(res.Body, &container); err != nil {
 		return nil, errors.Wrapf(err, "json decode list groups")
 	}
 	return container.Data, nil
@@ -185,143 +334,331 @@ func (c *Client) ListGroups(ctx context.Context, projectID string) ([]Group, err
 
 // GetGroup fetches a single group by id.
 func (c *Client) GetGroup(ctx context.Context, projectID, groupID string) (*Group, error) {
-	path := fmt.Sprintf("projects/%s/groups/%s", projectID, groupID)
+	path, err := pathf("projects/%s/groups/%s", c.project(projectID), groupID)
+	if err != nil {
+		return nil, err
+	}
 	uri := c.buildURL(path, nil)
-	res, err := c.request(http.MethodGet, uri.String(), nil)
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "http get request failed")
+		return nil, requestError(err, "http get request failed")
 	}
 	defer res.Body.Close()
 
-	// 4xx range
-	if res.StatusCode >= 400 && res.StatusCode < 500 {
-		return nil, decodeAPIError(res.Body)
-	}
-
-	return decodeGroupResponse(res.Body)
+	return c.decodeGroupResponse(res.Body)
 }
 
 // DeleteGroup deletes a group by id.
 func (c *Client) DeleteGroup(ctx context.Context, projectID, groupID string) error {
-	path := fmt.Sprintf("projects/%s/groups/%s", projectID, groupID)
+	path, err := pathf("projects/%s/groups/%s", c.project(projectID), groupID)
+	if err != nil {
+		return err
+	}
 	uri := c.buildURL(path, nil)
-	res, err := c.request(http.MethodDelete, uri.String(), nil)
+	res, err := c.request(ctx, http.MethodDelete, uri.String(), nil)
 	if err != nil {
-		return errors.Wrap(err, "http delete request failed")
+		return requestError(err, "http delete request failed")
 	}
 	defer res.Body.Close()
 
-	// 4xx range
-	if res.StatusCode >= 400 && res.StatusCode < 500 {
-		return decodeAPIError(res.Body)
-	}
-
 	return nil
 }
 
 // ListMail fetches a list of mail resources.
 func (c *Client) ListMail(ctx context.Context, projectID string) ([]Mail, error) {
 	// build the URL including query params
-	path := fmt.Sprintf("projects/%s/mail", projectID)
+	path, err := pathf("projects/%s/mail", c.project(projectID))
+	if err != nil {
+		return nil, err
+	}
 	uri := c.buildURL(path, nil)
 
-	res, err := c.request(http.MethodGet, uri.String(), nil)
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "http get request failed")
+		return nil, requestError(err, "http get request failed")
 	}
 	defer res.Body.Close()
 
-	// 4xx range
-	if res.StatusCode >= 400 && res.StatusCode < 500 {
-		return nil, decodeAPIError(res.Body)
-	}
-
 	// json decode
 	var container struct {
 		Data []Mail `json:"data"`
 	}
-	dec := json.NewDecoder(res.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&container); err != nil {
+	if err := c.decode(res.Body, &container); err != nil {
 		return nil, errors.Wrapf(err, "json decode list mail")
 	}
 	return container.Data, nil
 }
 
+// ListMailPage fetches a single page of mail resources matching params.
+func (c *Client) ListMailPage(ctx context.Context, projectID string, params ListMailParams) (*MailPage, error) {
+	// build the URL including query params
+	query := url.Values{}
+	if !params.Since.IsZero() {
+		query.Set("since", params.Since.UTC().Format(time.RFC3339Nano))
+	}
+	if !params.Until.IsZero() {
+		query.Set("until", params.Until.UTC().Format(time.RFC3339Nano))
+	}
+	if params.Status != "" {
+		query.Set("status", params.Status.String())
+	}
+	if params.Limit > 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.Cursor != "" {
+		query.Set("cursor", params.Cursor)
+	}
+	path, err := pathf("projects/%s/mail", c.project(projectID))
+	if err != nil {
+		return nil, err
+	}
+	uri := c.buildURL(path, query)
+
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return nil, requestError(err, "http get request failed")
+	}
+	defer res.Body.Close()
+
+	// json decode
+	var page MailPage
+	if err := c.decode(res.Body, &page); err != nil {
+		return nil, errors.Wrapf(err, "json decode list mail page")
+	}
+	return &page, nil
+}
+
 // ListMailLogs fetches a list of mail log resources for the given mail entry.
 func (c *Client) ListMailLogs(ctx context.Context, projectID, mailID string) ([]MailLog, error) {
 	// build the URL including query params
-	path := fmt.Sprintf("projects/%s/mail/%s/logs", projectID, mailID)
+	path, err := pathf("projects/%s/mail/%s/logs", c.project(projectID), mailID)
+	if err != nil {
+		return nil, err
+	}
 	uri := c.buildURL(path, nil)
 
-	res, err := c.request(http.MethodGet, uri.String(), nil)
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "http get request failed")
+		return nil, requestError(err, "http get request failed")
 	}
 	defer res.Body.Close()
 
-	// 4xx range
-	if res.StatusCode >= 400 && res.StatusCode < 500 {
-		return nil, decodeAPIError(res.Body)
+	// json decode
+	var container struct {
+		Data []MailLog `json:"data"`
+	}
+	if err := c.decode(res.Body, &container); err != nil {
+		return nil, errors.Wrapf(err, "json decode list mail logs")
+	}
+	return container.Data, nil
+}
+
+// ListProjectMailLogs fetches mail log resources across every mail entry in
+// the project. If since is non-zero only logs created after since are
+// returned.
+func (c *Client) ListProjectMailLogs(ctx context.Context, projectID string, since time.Time) ([]MailLog, error) {
+	// build the URL including query params
+	var query url.Values
+	if !since.IsZero() {
+		query = url.Values{
+			"since": []string{since.UTC().Format(time.RFC3339Nano)},
+		}
+	}
+	path, err := pathf("projects/%s/mail-logs", c.project(projectID))
+	if err != nil {
+		return nil, err
+	}
+	uri := c.buildURL(path, query)
+
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return nil, requestError(err, "http get request failed")
 	}
+	defer res.Body.Close()
 
 	// json decode
 	var container struct {
 		Data []MailLog `json:"data"`
 	}
-	dec := json.NewDecoder(res.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&container); err != nil {
-		return nil, errors.Wrapf(err, "json decode list mail logs")
+	if err := c.decode(res.Body, &container); err != nil {
+		return nil, errors.Wrapf(err, "json decode list project mail logs")
 	}
 	return container.Data, nil
 }
 
 // GetMail fetches a single mail resource.
 func (c *Client) GetMail(ctx context.Context, projectID, mailID string) (*Mail, error) {
-	path := fmt.Sprintf("projects/%s/mail/%s", projectID, mailID)
+	path, err := pathf("projects/%s/mail/%s", c.project(projectID), mailID)
+	if err != nil {
+		return nil, err
+	}
 	uri := c.buildURL(path, nil)
-	res, err := c.request(http.MethodGet, uri.String(), nil)
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "http get request failed")
+		return nil, requestError(err, "http get request failed")
 	}
 	defer res.Body.Close()
 
-	// 4xx range
-	if res.StatusCode >= 400 && res.StatusCode < 500 {
-		return nil, decodeAPIError(res.Body)
+	return c.decodeMailResponse(res.Body)
+}
+
+// GetMailContent fetches the rendered subject, text and HTML bodies and
+// the raw RFC 5322 source of a mail entry.
+func (c *Client) GetMailContent(ctx context.Context, projectID, mailID string) (*MailContent, error) {
+	path, err := pathf("projects/%s/mail/%s/content", c.project(projectID), mailID)
+	if err != nil {
+		return nil, err
+	}
+	uri := c.buildURL(path, nil)
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return nil, requestError(err, "http get request failed")
 	}
+	defer res.Body.Close()
 
-	return decodeMailResponse(res.Body)
+	var container struct {
+		Data *MailContent `json:"data"`
+	}
+	if err := c.decode(res.Body, &container); err != nil {
+		return nil, errors.Wrapf(err, "json decode get mail content")
+	}
+	if container.Data == nil {
+		return nil, errors.New("mail content response has no data")
+	}
+	return container.Data, nil
 }
 
-func decodeGroupResponse(r io.Reader) (*Group, error) {
+// SendMail sends a new mail rendered from a template. Recipients and
+// headers are validated, and attachments are read and checked against
+// MaxAttachmentSize and MaxTotalAttachmentSize, before the request is made.
+func (c *Client) SendMail(ctx context.Context, params *SendMailParams) (*Mail, error) {
+	to, err := validateRecipients(params)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := encodeAttachments(params.Attachments, params.Inline)
+	if err != nil {
+		return nil, err
+	}
+
+	// request body
+	req := sendMailRequest{
+		TemplateID:  params.TemplateID,
+		EmailTo:     to[0].Email,
+		To:          to,
+		Cc:          params.Cc,
+		Bcc:         params.Bcc,
+		Headers:     params.Headers,
+		Subject:     params.Subject,
+		Params:      params.Params,
+		Attachments: attachments,
+	}
+	if !params.SendAt.IsZero() {
+		sendAt := params.SendAt.UTC()
+		req.SendAt = &sendAt
+	}
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(&req); err != nil {
+		return nil, err
+	}
+
+	// do request
+	if params.IdempotencyKey != "" {
+		ctx = WithIdempotencyKey(ctx, params.IdempotencyKey)
+	}
+	path, err := pathf("projects/%s/mail", c.project(params.ProjectID))
+	if err != nil {
+		return nil, err
+	}
+	uri := c.buildURL(path, nil)
+	res, err := c.request(ctx, http.MethodPost, uri.String(), body)
+	if err != nil {
+		return nil, requestError(err, "http post request failed")
+	}
+	defer res.Body.Close()
+
+	return c.decodeMailResponse(res.Body)
+}
+
+// CancelMail cancels a mail entry that has not yet been sent. Only mail in
+// the pending or scheduled status can be cancelled.
+func (c *Client) CancelMail(ctx context.Context, projectID, mailID string) (*Mail, error) {
+	path, err := pathf("projects/%s/mail/%s/cancel", c.project(projectID), mailID)
+	if err != nil {
+		return nil, err
+	}
+	uri := c.buildURL(path, nil)
+	res, err := c.request(ctx, http.MethodPost, uri.String(), nil)
+	if err != nil {
+		return nil, requestError(err, fmt.Sprintf("http cancel mail (%s) request failed", mailID))
+	}
+	defer res.Body.Close()
+
+	return c.decodeMailResponse(res.Body)
+}
+
+// ResendMail redelivers an existing mail entry, optionally through a
+// different transport. The mail is returned to the pending status.
+func (c *Client) ResendMail(ctx context.Context, projectID, mailID string, opts ResendOptions) (*Mail, error) {
+	// request body
+	req := resendMailRequest{
+		TransportID: opts.TransportID,
+	}
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(&req); err != nil {
+		return nil, err
+	}
+
+	// do request
+	if opts.IdempotencyKey != "" {
+		ctx = WithIdempotencyKey(ctx, opts.IdempotencyKey)
+	}
+	path, err := pathf("projects/%s/mail/%s/resend", c.project(projectID), mailID)
+	if err != nil {
+		return nil, err
+	}
+	uri := c.buildURL(path, nil)
+	res, err := c.request(ctx, http.MethodPost, uri.String(), body)
+	if err != nil {
+		return nil, requestError(err, fmt.Sprintf("http resend mail (%s) request failed", mailID))
+	}
+	defer res.Body.Close()
+
+	return c.decodeMailResponse(res.Body)
+}
+
+func (c *Client) decodeGroupResponse(r io.Reader) (*Group, error) {
 	var container struct {
 		Data *Group `json:"data"`
 	}
-	dec := json.NewDecoder(r)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&container); err != nil {
+	if err := c.decode(r, &container); err != nil {
 		return nil, errors.Wrapf(err, "json decode get group")
 	}
+	if container.Data == nil {
+		return nil, errors.New("group response has no data")
+	}
 	return container.Data, nil
 }
 
-func decodeMailResponse(r io.Reader) (*Mail, error) {
+func (c *Client) decodeMailResponse(r io.Reader) (*Mail, error) {
 	var container struct {
 		Data *Mail `json:"data"`
 	}
-	dec := json.NewDecoder(r)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&container); err != nil {
+	if err := c.decode(r, &container); err != nil {
 		return nil, errors.Wrapf(err, "json decode get mail")
 	}
+	if container.Data == nil {
+		return nil, errors.New("mail response has no data")
+	}
 	return container.Data, nil
 }
 
 // CreateTemplate HTTP POST /templates/{id}
 func (c *Client) CreateTemplate(ctx context.Context, params *CreateTemplateParams) (*Template, error) {
-	path := fmt.Sprintf("projects/%s/templates/%s", params.ProjectID, params.ID)
+	path, err := pathf("projects/%s/templates/%s", c.project(params.ProjectID), params.ID)
+	if err != nil {
+		return nil, err
+	}
 	uri := c.buildURL(path, nil)
 
 	// request body
@@ -335,71 +672,67 @@ func (c *Client) CreateTemplate(ctx context.Context, params *CreateTemplateParam
 	}
 
 	// post
-	res, err := c.request(http.MethodPut, uri.String(), body)
+	res, err := c.request(ctx, http.MethodPut, uri.String(), body)
 	if err != nil {
-		return nil, errors.Wrap(err, "http post request failed")
+		return nil, requestError(err, "http post request failed")
 	}
 	defer res.Body.Close()
 
-	// 4xx range
-	if res.StatusCode >= 400 && res.StatusCode < 500 {
-		return nil, decodeAPIError(res.Body)
-	}
-
 	var container struct {
 		Data *Template `json:"data"`
 	}
-	dec := json.NewDecoder(res.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&container); err != nil {
+	if err := c.decode(res.Body, &container); err != nil {
 		return nil, errors.Wrapf(err, "json decode get template")
 	}
+	if container.Data == nil {
+		return nil, errors.New("template response has no data")
+	}
 	return container.Data, nil
 }
 
 // GetTemplate fetches a single template by id.
 func (c *Client) GetTemplate(ctx context.Context, projectID, templateID string) (*Template, error) {
-	path := fmt.Sprintf("projects/%s/templates/%s", projectID, templateID)
+	path, err := pathf("projects/%s/templates/%s", c.project(projectID), templateID)
+	if err != nil {
+		return nil, err
+	}
 	uri := c.buildURL(path, nil)
-	res, err := c.request(http.MethodGet, uri.String(), nil)
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "http get request failed")
+		return nil, requestError(err, "http get request failed")
 	}
 	defer res.Body.Close()
 
-	// 4xx range
-	if res.StatusCode >= 400 && res.StatusCode < 500 {
-		return nil, decodeAPIError(res.Body)
-	}
-
 	var container struct {
 		Data *Template `json:"data"`
 	}
-	dec := json.NewDecoder(res.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&container); err != nil {
+	if err := c.decode(res.Body, &container); err != nil {
 		return nil, errors.Wrapf(err, "json decode get template")
 	}
+	if container.Data == nil {
+		return nil, errors.New("template response has no data")
+	}
 	return container.Data, nil
 }
 
 // ListTemplates fetches a slice of templates for the current project.
 func (c *Client) ListTemplates(ctx context.Context, projectID string) ([]Template, error) {
 	// build the URL including query params
-	path := fmt.Sprintf("projects/%s/templates", projectID)
+	path, err := pathf("projects/%s/templates", c.project(projectID))
+	if err != nil {
+		return nil, err
+	}
 	uri := c.buildURL(path, nil)
-	res, err := c.request(http.MethodGet, uri.String(), nil)
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "http get request failed")
+		return nil, requestError(err, "http get request failed")
 	}
 	defer res.Body.Close()
 
 	var container struct {
 		Data []Template `json:"data"`
 	}
-	dec := json.NewDecoder(res.Body)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&container); err != nil {
+	if err := c.decode(res.Body, &container); err != nil {
 		return nil, errors.Wrapf(err, "json decode list templates")
 	}
 	return container.Data, nil
@@ -407,47 +740,423 @@ func (c *Client) ListTemplates(ctx context.Context, projectID string) ([]Templat
 
 // DeleteTemplate deletes the template with the given id.
 func (c *Client) DeleteTemplate(ctx context.Context, projectID, templateID string) error {
-	path := fmt.Sprintf("projects/%s/templates/%s", projectID, templateID)
+	path, err := pathf("projects/%s/templates/%s", c.project(projectID), templateID)
+	if err != nil {
+		return err
+	}
 	uri := c.buildURL(path, nil)
-	fmt.Println(uri)
 
-	res, err := c.request(http.MethodDelete, uri.String(), nil)
+	res, err := c.request(ctx, http.MethodDelete, uri.String(), nil)
 	if err != nil {
-		return errors.Wrapf(err, "http delete template (%s) request failed", templateID)
+		return requestError(err, fmt.Sprintf("http delete template (%s) request failed", templateID))
 	}
 	defer res.Body.Close()
 
-	// 4xx range
-	if res.StatusCode >= 400 && res.StatusCode < 500 {
-		return decodeAPIError(res.Body)
+	return nil
+}
+
+// CreateWebhook registers a new webhook endpoint for the given events.
+func (c *Client) CreateWebhook(ctx context.Context, params *CreateWebhookParams) (*Webhook, error) {
+	// request body
+	req := createWebhookRequest{
+		URL:    params.URL,
+		Events: params.Events,
+	}
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(&req); err != nil {
+		return nil, err
 	}
 
+	// do request
+	path, err := pathf("projects/%s/webhooks", c.project(params.ProjectID))
+	if err != nil {
+		return nil, err
+	}
+	uri := c.buildURL(path, nil)
+	res, err := c.request(ctx, http.MethodPost, uri.String(), body)
+	if err != nil {
+		return nil, requestError(err, "http post request failed")
+	}
+	defer res.Body.Close()
+
+	return c.decodeWebhookResponse(res.Body)
+}
+
+// ListWebhooks fetches a slice of webhook endpoints for the current project.
+func (c *Client) ListWebhooks(ctx context.Context, projectID string) ([]Webhook, error) {
+	path, err := pathf("projects/%s/webhooks", c.project(projectID))
+	if err != nil {
+		return nil, err
+	}
+	uri := c.buildURL(path, nil)
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
+	if err != nil {
+		return nil, requestError(err, "http get request failed")
+	}
+	defer res.Body.Close()
+
+	// json decode
+	var container struct {
+		Data []Webhook `json:"data"`
+	}
+	if err := c.decode(res.Body, &container); err != nil {
+		return nil, errors.Wrapf(err, "json decode list webhooks")
+	}
+	return container.Data, nil
+}
+
+// DeleteWebhook deletes a webhook endpoint by id.
+func (c *Client) DeleteWebhook(ctx context.Context, projectID, webhookID string) error {
+	path, err := pathf("projects/%s/webhooks/%s", c.project(projectID), webhookID)
+	if err != nil {
+		return err
+	}
+	uri := c.buildURL(path, nil)
+	res, err := c.request(ctx, http.MethodDelete, uri.String(), nil)
+	if err != nil {
+		return requestError(err, fmt.Sprintf("http delete webhook (%s) request failed", webhookID))
+	}
+	defer res.Body.Close()
+
 	return nil
 }
 
-func (c *Client) request(method, uri string, body io.Reader) (*http.Response, error) {
-	req, err := http.NewRequest(method, uri, body)
-	if method != http.MethodDelete {
-		req.Header.Set("Accept", "application/json")
+// RotateWebhookSecret replaces the signing secret of a webhook endpoint.
+// The returned webhook carries the new secret.
+func (c *Client) RotateWebhookSecret(ctx context.Context, projectID, webhookID string) (*Webhook, error) {
+	path, err := pathf("projects/%s/webhooks/%s/rotate-secret", c.project(projectID), webhookID)
+	if err != nil {
+		return nil, err
+	}
+	uri := c.buildURL(path, nil)
+	res, err := c.request(ctx, http.MethodPost, uri.String(), nil)
+	if err != nil {
+		return nil, requestError(err, "http post request failed")
 	}
-	// req.Header.Set("Authorization", "Bearer "+c.jwt)
+	defer res.Body.Close()
+
+	return c.decodeWebhookResponse(res.Body)
+}
 
-	if method == http.MethodPost || method == http.MethodPut {
-		req.Header.Set("Content-Type", "application/json")
+// SendTestEvent asks Raven Mailer to deliver a test event of the given type
+// to a webhook endpoint and reports how the endpoint responded. An empty
+// eventType sends a ping event.
+func (c *Client) SendTestEvent(ctx context.Context, projectID, webhookID, eventType string) (*WebhookTestResult, error) {
+	type sendTestEventRequest struct {
+		EventType string `json:"eventType,omitempty"`
 	}
-	res, err := c.client.Do(req)
+
+	// request body
+	req := sendTestEventRequest{
+		EventType: eventType,
+	}
+	body := new(bytes.Buffer)
+	if err := json.NewEncoder(body).Encode(&req); err != nil {
+		return nil, err
+	}
+
+	// do request
+	path, err := pathf("projects/%s/webhooks/%s/test", c.project(projectID), webhookID)
+	if err != nil {
+		return nil, err
+	}
+	uri := c.buildURL(path, nil)
+	res, err := c.request(ctx, http.MethodPost, uri.String(), body)
+	if err != nil {
+		return nil, requestError(err, "http post request failed")
+	}
+	defer res.Body.Close()
+
+	var container struct {
+		Data *WebhookTestResult `json:"data"`
+	}
+	if err := c.decode(res.Body, &container); err != nil {
+		return nil, errors.Wrapf(err, "json decode send test event")
+	}
+	if container.Data == nil {
+		return nil, errors.New("test event response has no data")
+	}
+	return container.Data, nil
+}
+
+func (c *Client) decodeWebhookResponse(r io.Reader) (*Webhook, error) {
+	var container struct {
+		Data *Webhook `json:"data"`
+	}
+	if err := c.decode(r, &container); err != nil {
+		return nil, errors.Wrapf(err, "json decode get webhook")
+	}
+	if container.Data == nil {
+		return nil, errors.New("webhook response has no data")
+	}
+	return container.Data, nil
+}
+
+func (c *Client) request(ctx context.Context, method, uri string, body io.Reader) (*http.Response, error) {
+	var cacheKey string
+	var cached *CacheEntry
+	if method == http.MethodGet && c.cache != nil {
+		cacheKey = c.cacheKey(uri)
+		if e, ok := c.cache.Get(cacheKey); ok && e != nil && e.ETag != "" {
+			cached = e
+		}
+	}
+
+	// the same key is sent on every attempt so that the server can
+	// recognise retries
+	key := idempotencyKey(ctx, method)
+
+	// buffer the body so that it can be sent again on retry
+	var payload []byte
+	if body != nil {
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read HTTP %s request body", method)
+		}
+		payload = b
+	}
+
+	project := c.pool.projectOf(uri)
+	failovers := 0
+	refetched := false
+	for attempt := 0; ; {
+		ep := c.pool.pick(project)
+		req, err := http.NewRequestWithContext(ctx, method, c.pool.rebase(uri, ep), nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "new HTTP %s request", method)
+		}
+		if payload != nil {
+			req.Body = io.NopCloser(bytes.NewReader(payload))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(payload)), nil
+			}
+			req.ContentLength = int64(len(payload))
+		}
+		c.setHeaders(req)
+		if method != http.MethodDelete {
+			req.Header.Set("Accept", "application/json")
+		}
+		if method == http.MethodPost || method == http.MethodPut {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		switch {
+		case refetched:
+			req.Header.Set("Cache-Control", "no-cache")
+		case cached != nil:
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+
+		res, failover, reason, err := c.roundTrip(ctx, c.client, req, ep)
+		if errors.Is(err, ErrCircuitOpen) {
+			return nil, err
+		}
+		if failover && failovers < len(c.pool.endpoints)-1 && retryable(method, key, res, err) {
+			if res != nil {
+				io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+			}
+			if c.logger != nil {
+				c.logger.Printf("raven: %s %s: %s, failing over to the next endpoint",
+					method, req.URL, reason)
+			}
+			failovers++
+			continue
+		}
+
+		if attempt < c.retries && ctx.Err() == nil && retryable(method, key, res, err) {
+			wait := c.retryDelay(attempt, res)
+			if res != nil {
+				io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+			}
+			if c.logger != nil {
+				c.logger.Printf("raven: retrying %s %s in %s (attempt %d of %d)",
+					method, uri, wait, attempt+1, c.retries)
+			}
+			t := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return nil, errors.Wrapf(ctx.Err(), "do HTTP %s request", method)
+			case <-t.C:
+			}
+			attempt++
+			continue
+		}
+		if err != nil {
+			if key != "" {
+				return nil, errors.Wrapf(err, "do HTTP %s request (idempotency key %s)", req.Method, key)
+			}
+			return nil, errors.Wrapf(err, "do HTTP %s request", req.Method)
+		}
+		if res.StatusCode >= 400 {
+			defer res.Body.Close()
+			return nil, decodeAPIError(res)
+		}
+		if res.StatusCode == http.StatusNotModified && (cached == nil || refetched) {
+			// there is no cached body to serve, e.g. a proxy answered from
+			// its own cache, so ask once more for the full response
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+			if refetched {
+				return nil, errors.Errorf("do HTTP %s request: %s without a cached response", method, res.Status)
+			}
+			refetched = true
+			continue
+		}
+
+		if method == http.MethodGet && c.cache != nil {
+			return c.cacheResponse(cacheKey, cached, res)
+		}
+		return res, nil
+	}
+}
+
+// roundTrip sends req to endpoint ep of the pool using hc. The request
+// passes through the circuit breaker, is logged, and its outcome is
+// reported to the endpoint pool and the response metadata hooks. failover
+// is true if ep was marked unhealthy and another endpoint can be tried.
+func (c *Client) roundTrip(ctx context.Context, hc *http.Client, req *http.Request, ep int) (res *http.Response, failover bool, reason string, err error) {
+	var gen uint64
+	if c.breaker != nil {
+		if gen, err = c.breaker.allow(); err != nil {
+			return nil, false, "", err
+		}
+	}
+	start := time.Now()
+	res, err = hc.Do(req)
+	if c.breaker != nil {
+		if ctx.Err() != nil {
+			c.breaker.release(gen)
+		} else {
+			c.breaker.record(gen, err == nil && res.StatusCode < 500)
+		}
+	}
+	c.logRequest(req, res, err, time.Since(start))
+
+	var failed bool
+	failed, reason = endpointFailed(res, err)
+	failover = c.pool.report(ep, failed && ctx.Err() == nil, reason)
+	if err == nil {
+		c.recordMeta(ctx, res)
+	}
+	return res, failover, reason, err
+}
+
+// setHeaders applies the base headers, user agent and credentials.
+func (c *Client) setHeaders(req *http.Request) {
+	for k, v := range c.headers {
+		req.Header[k] = append([]string(nil), v...)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	if c.auth != nil {
+		c.auth(req)
+	}
+}
+
+// retryable reports whether a request may be sent again. Only idempotent
+// methods, or requests carrying an idempotency key, are retried.
+func retryable(method, key string, res *http.Response, err error) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+	default:
+		if key == "" {
+			return false
+		}
+	}
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// endpointFailed reports whether a response or error means the endpoint
+// should be marked unhealthy, and why.
+func endpointFailed(res *http.Response, err error) (bool, string) {
 	if err != nil {
-		return nil, errors.Wrapf(err, "do HTTP %s request", req.Method)
+		return true, err.Error()
+	}
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, res.Status
 	}
-	return res, nil
+	return false, ""
 }
 
-func decodeAPIError(r io.Reader) error {
+// retryDelay returns the wait before the next attempt, preferring the
+// server's Retry-After header.
+func (c *Client) retryDelay(attempt int, res *http.Response) time.Duration {
+	if res != nil {
+		if s, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil && s >= 0 {
+			d := time.Duration(s) * time.Second
+			if d > DefaultMaxRetryBackoff {
+				d = DefaultMaxRetryBackoff
+			}
+			return d
+		}
+	}
+	d := c.retryBackoff << uint(attempt)
+	if d <= 0 || d > DefaultMaxRetryBackoff {
+		d = DefaultMaxRetryBackoff
+	}
+	return d
+}
+
+func (c *Client) logRequest(req *http.Request, res *http.Response, err error, d time.Duration) {
+	if c.logger == nil {
+		return
+	}
+	if err != nil {
+		c.logger.Printf("raven: %s %s failed after %s: %v", req.Method, req.URL, d.Round(time.Millisecond), err)
+		return
+	}
+	c.logger.Printf("raven: %s %s %d (%s)", req.Method, req.URL, res.StatusCode, d.Round(time.Millisecond))
+}
+
+// maxErrorBodyBytes is the most of an error response body that is read.
+const maxErrorBodyBytes = 64 << 10
+
+// decodeAPIError decodes an error response. Unknown fields are always
+// ignored so that the API error itself is not masked by schema drift. A
+// body that is not a Raven Mailer error, such as an HTML page from a
+// proxy, is reported as an APIError carrying the status code.
+func decodeAPIError(res *http.Response) error {
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxErrorBodyBytes))
+	if err != nil {
+		return errors.Wrapf(err, "read HTTP %d error response", res.StatusCode)
+	}
 	var apiErr APIError
-	dec := json.NewDecoder(r)
-	dec.DisallowUnknownFields()
-	if err := dec.Decode(&apiErr); err != nil {
-		return err
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Status == 0 && apiErr.Code == "" {
+		apiErr = APIError{Message: http.StatusText(res.StatusCode)}
+	}
+	if apiErr.Status == 0 {
+		apiErr.Status = res.StatusCode
+	}
+	apiErr.header = res.Header
+	if res.Request != nil {
+		apiErr.IdempotencyKey = res.Request.Header.Get(IdempotencyKeyHeader)
+	}
+	if apiErr.RequestID == "" {
+		apiErr.RequestID = res.Header.Get(RequestIDHeader)
 	}
 	return &apiErr
 }
+
+// requestError wraps an error returned by request with msg. An *APIError
+// is returned as is so that callers can inspect it.
+func requestError(err error, msg string) error {
+	if apiErr, ok := err.(*APIError); ok {
+		return apiErr
+	}
+	return errors.Wrap(err, msg)
+}
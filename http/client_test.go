@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, h http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c, err := NewClientWithOptions(srv.URL+"/v1", opts...)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	return c
+}
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+	}{
+		{"client error", 404, "application/json",
+			`{"status":404,"code":"mail/mail-not-found","message":"mail not found"}`, 404, ErrCodeMailNotFound},
+		{"server error", 500, "application/json",
+			`{"status":500,"code":"internal","message":"database unavailable"}`, 500, "internal"},
+		{"server error with unknown fields", 500, "application/json",
+			`{"status":500,"code":"internal","message":"boom","trace":"abc"}`, 500, "internal"},
+		{"proxy error page", 502, "text/html",
+			`<html><body>Bad Gateway</body></html>`, 502, ""},
+		{"empty body", 503, "", ``, 503, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("X-Request-Id", "req-1")
+				if tc.contentType != "" {
+					w.Header().Set("Content-Type", tc.contentType)
+				}
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			})
+
+			mail, err := c.GetMail(context.Background(), "p1", "m1")
+			if mail != nil {
+				t.Fatalf("GetMail returned mail %+v with error %v", mail, err)
+			}
+			apiErr, ok := err.(*APIError)
+			if !ok {
+				t.Fatalf("GetMail error = %T %v, want *APIError", err, err)
+			}
+			if apiErr.Status != tc.wantStatus || apiErr.Code != tc.wantCode {
+				t.Errorf("APIError status %d code %q, want %d %q",
+					apiErr.Status, apiErr.Code, tc.wantStatus, tc.wantCode)
+			}
+			if apiErr.RequestID != "req-1" {
+				t.Errorf("APIError request id %q, want req-1", apiErr.RequestID)
+			}
+		})
+	}
+}
+
+func TestMissingData(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+	ctx := context.Background()
+
+	if m, err := c.GetMail(ctx, "p1", "m1"); err == nil || m != nil {
+		t.Errorf("GetMail = %v, %v, want error", m, err)
+	}
+	if g, err := c.GetGroup(ctx, "p1", "g1"); err == nil || g != nil {
+		t.Errorf("GetGroup = %v, %v, want error", g, err)
+	}
+	if s, err := c.GetStats(ctx, "p1", StatsParams{}); err == nil || s != nil {
+		t.Errorf("GetStats = %v, %v, want error", s, err)
+	}
+}
+
+func TestWaitForMailServerError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"status":500,"code":"internal","message":"boom"}`)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := c.WaitForMail(ctx, "p1", "m1", WaitOptions{MinInterval: time.Millisecond})
+	if err == nil {
+		t.Fatal("WaitForMail returned no error for a 500 response")
+	}
+}
+
+func TestRetries(t *testing.T) {
+	createGroup := func(ctx context.Context, c *Client) error {
+		_, err := c.CreateGroup(ctx, "p1", "Onboarding")
+		return err
+	}
+	deleteGroup := func(ctx context.Context, c *Client) error {
+		return c.DeleteGroup(ctx, "p1", "g1")
+	}
+	tests := []struct {
+		name      string
+		call      func(ctx context.Context, c *Client) error
+		callerKey string
+		wantKey   bool
+	}{
+		{"POST with generated key", createGroup, "", true},
+		{"POST with caller key", createGroup, "group-1", true},
+		{"DELETE", deleteGroup, "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var keys []string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+				if len(keys) < 3 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprint(w, `{"data":{"id":"g1","projectId":"p1","name":"Onboarding"}}`)
+			}, WithRetries(2, time.Millisecond))
+
+			ctx := context.Background()
+			if tc.callerKey != "" {
+				ctx = WithIdempotencyKey(ctx, tc.callerKey)
+			}
+			if err := tc.call(ctx, c); err != nil {
+				t.Fatalf("request failed after retries: %v", err)
+			}
+			if len(keys) != 3 {
+				t.Fatalf("server received %d attempts, want 3", len(keys))
+			}
+			if (keys[0] != "") != tc.wantKey {
+				t.Errorf("Idempotency-Key %q, want key %v", keys[0], tc.wantKey)
+			}
+			if tc.callerKey != "" && keys[0] != tc.callerKey {
+				t.Errorf("Idempotency-Key %q, want %q", keys[0], tc.callerKey)
+			}
+			for _, k := range keys[1:] {
+				if k != keys[0] {
+					t.Errorf("retry sent Idempotency-Key %q, first attempt sent %q", k, keys[0])
+				}
+			}
+		})
+	}
+}
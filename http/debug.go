@@ -0,0 +1,278 @@
+package http
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptrace"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// maxDebugBody is the number of body bytes written by the debug dump.
+const maxDebugBody = 16 << 10
+
+// redacted replaces secret values in the debug dump.
+const redacted = "REDACTED"
+
+// debugRedactHeaders headers whose values are never dumped.
+var debugRedactHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+// debugRedactKeys JSON keys whose values are never dumped.
+var debugRedactKeys = map[string]bool{
+	"password": true,
+	"secret":   true,
+	"token":    true,
+	"content":  true, // attachment data
+	"raw":      true, // message source, including recipient headers
+	"txt":      true, // message bodies
+	"html":     true,
+}
+
+// debugAddressKeys JSON keys holding recipient addresses. Values within
+// them are masked, and display names redacted.
+var debugAddressKeys = map[string]bool{
+	"emailto": true,
+	"to":      true,
+	"cc":      true,
+	"bcc":     true,
+}
+
+// debugEmailPattern matches email addresses in free text such as mail log
+// messages, e.g. "550 5.1.1 <ann@example.com>: Recipient address rejected".
+// The domain is captured so that it can be kept.
+var debugEmailPattern = regexp.MustCompile(`[A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@([A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*)`)
+
+// WithDebug dumps each request and response, including headers and bodies,
+// to w along with DNS, connect, TLS and time to first byte timings.
+// Credentials, transport passwords, webhook secrets, message sources and
+// bodies, and recipient names are redacted, and email addresses anywhere
+// in a body are masked.
+func WithDebug(w io.Writer) Option {
+	return func(o *options) error {
+		if w == nil {
+			return errors.New("debug writer must not be nil")
+		}
+		o.debug = w
+		return nil
+	}
+}
+
+// debugTransport dumps requests and responses passing through next.
+type debugTransport struct {
+	next http.RoundTripper
+	mu   sync.Mutex
+	w    io.Writer
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.GetBody != nil {
+		if rc, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(rc)
+			rc.Close()
+		}
+	}
+
+	tm := &phaseTimings{start: time.Now()}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), tm.trace()))
+	res, err := t.next.RoundTrip(req)
+	tm.mu.Lock()
+	tm.done = time.Now()
+	tm.mu.Unlock()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "> %s %s %s\n", req.Method, req.URL.RequestURI(), req.Proto)
+	fmt.Fprintf(&b, "> Host: %s\n", req.URL.Host)
+	writeDebugHeaders(&b, "> ", req.Header)
+	writeDebugBody(&b, "> ", req.Header.Get("Content-Type"), reqBody)
+
+	if err != nil {
+		fmt.Fprintf(&b, "* error: %v\n", err)
+	} else {
+		fmt.Fprintf(&b, "< %s %s\n", res.Proto, res.Status)
+		writeDebugHeaders(&b, "< ", res.Header)
+		ct := res.Header.Get("Content-Type")
+		if mt, _, _ := mime.ParseMediaType(ct); mt == "text/event-stream" {
+			fmt.Fprintf(&b, "< (event stream not dumped)\n")
+		} else {
+			body, rerr := io.ReadAll(res.Body)
+			res.Body.Close()
+			res.Body = io.NopCloser(bytes.NewReader(body))
+			if rerr != nil {
+				fmt.Fprintf(&b, "* error reading body: %v\n", rerr)
+			}
+			writeDebugBody(&b, "< ", ct, body)
+		}
+	}
+	fmt.Fprintf(&b, "* %s\n\n", tm)
+
+	t.mu.Lock()
+	t.w.Write(b.Bytes())
+	t.mu.Unlock()
+	return res, err
+}
+
+func writeDebugHeaders(w io.Writer, prefix string, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			if debugRedactHeaders[http.CanonicalHeaderKey(k)] {
+				v = redacted
+			}
+			fmt.Fprintf(w, "%s%s: %s\n", prefix, k, v)
+		}
+	}
+}
+
+func writeDebugBody(w io.Writer, prefix, contentType string, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	if mt, _, _ := mime.ParseMediaType(contentType); mt == "application/json" || json.Valid(body) {
+		var v interface{}
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err == nil {
+			if b, err := json.Marshal(redactJSON(v, false)); err == nil {
+				body = b
+			}
+		}
+	} else {
+		body = []byte(fmt.Sprintf("(%d bytes of %s)", len(body), contentType))
+	}
+
+	n := len(body)
+	if n > maxDebugBody {
+		body = body[:maxDebugBody]
+	}
+	fmt.Fprintf(w, "%s\n%s%s\n", prefix, prefix, body)
+	if n > maxDebugBody {
+		fmt.Fprintf(w, "%s... %d bytes truncated\n", prefix, n-maxDebugBody)
+	}
+}
+
+// redactJSON returns a copy of v with secret values and recipient names
+// replaced and email addresses masked.
+func redactJSON(v interface{}, address bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, vv := range t {
+			lk := strings.ToLower(k)
+			switch {
+			case debugRedactKeys[lk], address && lk == "name":
+				out[k] = redacted
+			default:
+				out[k] = redactJSON(vv, address || debugAddressKeys[lk])
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, vv := range t {
+			out[i] = redactJSON(vv, address)
+		}
+		return out
+	case string:
+		if address {
+			return maskAddress(t)
+		}
+		return debugEmailPattern.ReplaceAllString(t, "***@$1")
+	}
+	return v
+}
+
+// maskAddress hides the local part and any display name of an email
+// address, leaving the domain to help diagnose routing problems. Values
+// that are not addresses are redacted.
+func maskAddress(s string) string {
+	i := strings.LastIndex(s, "@")
+	if i < 0 {
+		return redacted
+	}
+	domain := s[i+1:]
+	if j := strings.IndexAny(domain, "> "); j >= 0 {
+		domain = domain[:j]
+	}
+	return "***@" + domain
+}
+
+// phaseTimings records the connection phases of a request via httptrace.
+type phaseTimings struct {
+	mu sync.Mutex
+
+	start, done               time.Time
+	dnsStart, dnsDone         time.Time
+	connectStart, connectDone time.Time
+	tlsStart, tlsDone         time.Time
+	firstByte                 time.Time
+	reused                    bool
+}
+
+func (p *phaseTimings) trace() *httptrace.ClientTrace {
+	now := func(t *time.Time) {
+		p.mu.Lock()
+		if t.IsZero() {
+			*t = time.Now()
+		}
+		p.mu.Unlock()
+	}
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { now(&p.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { now(&p.dnsDone) },
+		ConnectStart:      func(string, string) { now(&p.connectStart) },
+		ConnectDone:       func(string, string, error) { now(&p.connectDone) },
+		TLSHandshakeStart: func() { now(&p.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { now(&p.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.mu.Lock()
+			p.reused = info.Reused
+			p.mu.Unlock()
+		},
+		GotFirstResponseByte: func() { now(&p.firstByte) },
+	}
+}
+
+// String summarises the phases, e.g.
+// dns 2ms, connect 11ms, tls 24ms, first byte 61ms, total 63ms.
+func (p *phaseTimings) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var s []string
+	phase := func(name string, from, to time.Time) {
+		if !from.IsZero() && !to.IsZero() {
+			s = append(s, fmt.Sprintf("%s %s", name, to.Sub(from).Round(time.Microsecond)))
+		}
+	}
+	phase("dns", p.dnsStart, p.dnsDone)
+	phase("connect", p.connectStart, p.connectDone)
+	phase("tls", p.tlsStart, p.tlsDone)
+	phase("first byte", p.start, p.firstByte)
+	phase("total", p.start, p.done)
+	if p.reused {
+		s = append(s, "connection reused")
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDebugRedactsMailContent(t *testing.T) {
+	const body = `{"data":{"mailId":"m1","subject":"Your receipt",` +
+		`"txt":"Hi Alice, your card ending 4242 was charged",` +
+		`"html":"<p>Hi Alice, your card ending 4242 was charged</p>",` +
+		`"raw":"To: alice@example.com\r\nSubject: Your receipt\r\n\r\nHi Alice, your card ending 4242 was charged"}}`
+
+	var dump bytes.Buffer
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}, WithDebug(&dump), WithBearerToken("raven-key"))
+
+	content, err := c.GetMailContent(context.Background(), "p1", "m1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content.Raw, "alice@example.com") {
+		t.Errorf("debug dump altered the response, raw = %q", content.Raw)
+	}
+
+	out := dump.String()
+	for _, leak := range []string{"alice@example.com", "Alice", "4242", "raven-key"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("debug dump contains %q:\n%s", leak, out)
+		}
+	}
+	if !strings.Contains(out, "Your receipt") {
+		t.Errorf("debug dump is missing the subject:\n%s", out)
+	}
+}
+
+func TestDebugMasksAddresses(t *testing.T) {
+	const body = `{"data":[{"id":"m1","emailTo":"Ann Smith <ann.smith@example.com>",` +
+		`"to":[{"name":"Ann Smith","email":"ann.smith@example.com"}],` +
+		`"cc":[{"name":"Bob Jones","email":"bob@example.net"}],` +
+		`"bcc":[{"email":"carol+audit@example.org"}]}],` +
+		`"logs":[{"id":"l1","smtpCode":550,` +
+		`"message":"550 5.1.1 <dave_99@mail.example.io>: Recipient address rejected"}]}`
+
+	var dump bytes.Buffer
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}, WithDebug(&dump))
+	if _, err := c.ListMail(context.Background(), "p1"); err != nil {
+		t.Fatal(err)
+	}
+
+	out := dump.String()
+	for _, leak := range []string{"Ann", "Smith", "ann.smith@", "Bob", "Jones", "bob@", "carol", "dave_99"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("debug dump contains %q:\n%s", leak, out)
+		}
+	}
+	for _, want := range []string{"***@example.com", "***@example.net", "***@example.org",
+		"***@mail.example.io", "Recipient address rejected"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("debug dump is missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package http
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaDriftError is returned in strict decoding mode when a response
+// contains fields the client does not know about. The response is
+// rejected, so the call returns no data; the error, which may be wrapped,
+// lists the unknown fields. Use errors.As to retrieve it.
+type SchemaDriftError struct {
+	// Type is the Go type being decoded, e.g. http.Mail.
+	Type string
+
+	// Fields are the JSON paths of the unknown fields relative to the
+	// response body, e.g. data[].trackingId.
+	Fields []string
+}
+
+// Error string representation of a SchemaDriftError.
+func (e *SchemaDriftError) Error() string {
+	return "schema drift decoding " + e.Type + ": unknown fields " + strings.Join(e.Fields, ", ")
+}
+
+// decode decodes the JSON document in r into v. Unknown fields are ignored
+// unless the client uses strict decoding, in which case a *SchemaDriftError
+// listing them is returned.
+func (c *Client) decode(r io.Reader, v interface{}) error {
+	if !c.strict {
+		return json.NewDecoder(r).Decode(v)
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return err
+	}
+
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	t := reflect.TypeOf(v)
+	unknownFields(t, doc, "", seen)
+	if len(seen) == 0 {
+		return nil
+	}
+	fields := make([]string, 0, len(seen))
+	for f := range seen {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return &SchemaDriftError{Type: driftTypeName(t), Fields: fields}
+}
+
+// driftTypeName names the type being decoded, looking through pointers,
+// slices and the anonymous {"data": ...} container used by most endpoints.
+func driftTypeName(t reflect.Type) string {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice:
+			t = t.Elem()
+			continue
+		case reflect.Struct:
+			if t.Name() == "" && t.NumField() == 1 {
+				t = t.Field(0).Type
+				continue
+			}
+		}
+		return t.String()
+	}
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// unknownFields walks doc alongside t and records the path of every object
+// key that has no corresponding struct field.
+func unknownFields(t reflect.Type, doc interface{}, path string, out map[string]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for k, v := range obj {
+			f, ok := lookupField(fields, k)
+			if !ok {
+				out[joinPath(path, k)] = true
+				continue
+			}
+			unknownFields(f.Type, v, joinPath(path, k), out)
+		}
+	case reflect.Map:
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for k, v := range obj {
+			unknownFields(t.Elem(), v, joinPath(path, k), out)
+		}
+	case reflect.Slice, reflect.Array:
+		arr, ok := doc.([]interface{})
+		if !ok {
+			return
+		}
+		for _, v := range arr {
+			unknownFields(t.Elem(), v, path+"[]", out)
+		}
+	}
+}
+
+// jsonFields maps the JSON names of t's fields, including those promoted
+// from embedded structs, to the fields.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			et := f.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				for k, v := range jsonFields(et) {
+					if _, ok := fields[k]; !ok {
+						fields[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// lookupField finds the field for key, matching case-insensitively as
+// encoding/json does.
+func lookupField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+	if f, ok := fields[key]; ok {
+		return f, true
+	}
+	for k, f := range fields {
+		if strings.EqualFold(k, key) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
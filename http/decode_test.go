@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestStrictDecoding(t *testing.T) {
+	const mail = `{"id":"m1","templateId":"welcome","projectId":"p1","status":"delivered",` +
+		`"trackingId":"t1"}`
+	h := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/projects/p1/mail/m1":
+			fmt.Fprintf(w, `{"data":%s}`, mail)
+		default:
+			fmt.Fprintf(w, `{"data":[%s],"total":1}`, mail)
+		}
+	}
+	ctx := context.Background()
+
+	t.Run("lenient", func(t *testing.T) {
+		c := newTestClient(t, h)
+		m, err := c.GetMail(ctx, "p1", "m1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.ID != "m1" || m.Status != MailStatusDelivered {
+			t.Errorf("GetMail = %+v", m)
+		}
+		if _, err := c.ListMail(ctx, "p1"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		c := newTestClient(t, h, WithStrictDecoding())
+
+		m, err := c.GetMail(ctx, "p1", "m1")
+		var drift *SchemaDriftError
+		if !errors.As(err, &drift) {
+			t.Fatalf("GetMail error = %v, want *SchemaDriftError", err)
+		}
+		if m != nil {
+			t.Errorf("GetMail returned %+v with a schema drift error", m)
+		}
+		if drift.Type != "http.Mail" || !reflect.DeepEqual(drift.Fields, []string{"data.trackingId"}) {
+			t.Errorf("drift = %+v, want http.Mail data.trackingId", drift)
+		}
+
+		_, err = c.ListMail(ctx, "p1")
+		if !errors.As(err, &drift) {
+			t.Fatalf("ListMail error = %v, want *SchemaDriftError", err)
+		}
+		if want := []string{"data[].trackingId", "total"}; !reflect.DeepEqual(drift.Fields, want) {
+			t.Errorf("ListMail drift fields %v, want %v", drift.Fields, want)
+		}
+	})
+}
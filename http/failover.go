@@ -0,0 +1,243 @@
+package http
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultEndpointCooldown is how long an endpoint that failed is skipped
+// before it is tried again.
+const DefaultEndpointCooldown = 30 * time.Second
+
+// WithFailover adds fallback endpoints, in order of preference, that are
+// used while the endpoint passed to NewClientWithOptions, or an earlier
+// fallback, is unhealthy. An endpoint is marked unhealthy for the cooldown
+// (see WithEndpointCooldown) when a request to it fails to connect or the
+// server responds 502, 503 or 504. Requests that may be retried (see
+// WithRetries) are then sent to the next healthy endpoint straight away.
+//
+// Routing is sticky per project: once a project has moved to a fallback
+// endpoint it stays there until that endpoint fails, so reads follow the
+// writes that preceded them.
+func WithFailover(endpoints ...string) Option {
+	return func(o *options) error {
+		for _, e := range endpoints {
+			u, err := parseEndpoint(e)
+			if err != nil {
+				return err
+			}
+			o.failover = append(o.failover, u)
+		}
+		return nil
+	}
+}
+
+// WithEndpointCooldown sets how long a failed endpoint is skipped. Left
+// unset defaults to DefaultEndpointCooldown.
+func WithEndpointCooldown(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return errors.Errorf("endpoint cooldown %s must be positive", d)
+		}
+		o.cooldown = d
+		return nil
+	}
+}
+
+// parseEndpoint parses and checks an API endpoint URL.
+func parseEndpoint(endpoint string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "url parse")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("endpoint %q must use the http or https scheme", endpoint)
+	}
+	if u.Host == "" {
+		return nil, errors.Errorf("endpoint %q has no host", endpoint)
+	}
+	return u, nil
+}
+
+// EndpointStatus health and usage of one endpoint. See Client.Endpoints.
+type EndpointStatus struct {
+	URL            string
+	Active         bool // used for new requests without a sticky project
+	Healthy        bool
+	UnhealthyUntil time.Time
+	Requests       int
+	Failures       int
+	LastError      string
+}
+
+type endpointState struct {
+	url            *url.URL
+	base           string // URL with a trailing slash, see buildURL
+	root           string // unversioned API root, see rootURL
+	unhealthyUntil time.Time
+	requests       int
+	failures       int
+	lastError      string
+}
+
+// endpointPool chooses the endpoint for each request.
+type endpointPool struct {
+	mu        sync.Mutex
+	endpoints []*endpointState
+	cooldown  time.Duration
+	sticky    map[string]int
+
+	now func() time.Time
+}
+
+func newEndpointPool(urls []*url.URL, cooldown time.Duration) *endpointPool {
+	if cooldown <= 0 {
+		cooldown = DefaultEndpointCooldown
+	}
+	p := &endpointPool{
+		cooldown: cooldown,
+		sticky:   make(map[string]int),
+		now:      time.Now,
+	}
+	for _, u := range urls {
+		b := *u
+		b.RawQuery = ""
+		b.Fragment = ""
+		if path := b.EscapedPath(); !strings.HasSuffix(path, "/") {
+			b.RawPath = path + "/"
+			b.Path = b.Path + "/"
+		}
+		p.endpoints = append(p.endpoints, &endpointState{url: u, base: b.String(), root: apiRoot(u).String()})
+	}
+	return p
+}
+
+// pick returns the index of the endpoint to use for project and makes it
+// sticky.
+func (p *endpointPool) pick(project string) int {
+	if len(p.endpoints) == 1 {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := p.choose(project, p.now())
+	p.sticky[project] = i
+	return i
+}
+
+// choose prefers the project's sticky endpoint, then the first healthy
+// endpoint, then the one whose cooldown ends first.
+func (p *endpointPool) choose(project string, now time.Time) int {
+	if i, ok := p.sticky[project]; ok && !now.Before(p.endpoints[i].unhealthyUntil) {
+		return i
+	}
+	best := 0
+	for i, e := range p.endpoints {
+		if !now.Before(e.unhealthyUntil) {
+			return i
+		}
+		if e.unhealthyUntil.Before(p.endpoints[best].unhealthyUntil) {
+			best = i
+		}
+	}
+	return best
+}
+
+// report records the outcome of a request to endpoint i. It returns true
+// if the endpoint was marked unhealthy and another endpoint is healthy.
+func (p *endpointPool) report(i int, failed bool, reason string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e := p.endpoints[i]
+	e.requests++
+	if !failed {
+		return false
+	}
+	e.failures++
+	e.lastError = reason
+	now := p.now()
+	e.unhealthyUntil = now.Add(p.cooldown)
+	for j, o := range p.endpoints {
+		if j != i && !now.Before(o.unhealthyUntil) {
+			return true
+		}
+	}
+	return false
+}
+
+// rebase moves uri, built against the primary endpoint, onto endpoint i.
+func (p *endpointPool) rebase(uri string, i int) string {
+	if i == 0 {
+		return uri
+	}
+	primary := p.endpoints[0]
+	switch {
+	case strings.HasPrefix(uri, primary.base):
+		return p.endpoints[i].base + uri[len(primary.base):]
+	case strings.HasPrefix(uri, primary.root):
+		return p.endpoints[i].root + uri[len(primary.root):]
+	}
+	return uri
+}
+
+// projectOf returns the project ID from a uri built against the primary
+// endpoint, used as the sticky routing key. URIs outside projects/{id},
+// such as the project list or server info, return an empty string.
+func (p *endpointPool) projectOf(uri string) string {
+	prefix := p.endpoints[0].base + "projects/"
+	if !strings.HasPrefix(uri, prefix) {
+		return ""
+	}
+	rest := uri[len(prefix):]
+	if i := strings.IndexAny(rest, "/?"); i >= 0 {
+		rest = rest[:i]
+	}
+	id, err := url.PathUnescape(rest)
+	if err != nil {
+		return rest
+	}
+	return id
+}
+
+func (p *endpointPool) status(project string) []EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	active := p.choose(project, now)
+	list := make([]EndpointStatus, len(p.endpoints))
+	for i, e := range p.endpoints {
+		list[i] = EndpointStatus{
+			URL:       e.url.String(),
+			Active:    i == active,
+			Healthy:   !now.Before(e.unhealthyUntil),
+			Requests:  e.requests,
+			Failures:  e.failures,
+			LastError: e.lastError,
+		}
+		if !list[i].Healthy {
+			list[i].UnhealthyUntil = e.unhealthyUntil
+		}
+	}
+	return list
+}
+
+// Endpoints returns the health and usage of each endpoint in order of
+// preference, for export as metrics.
+func (c *Client) Endpoints() []EndpointStatus {
+	return c.pool.status(c.projectID)
+}
+
+// ActiveEndpoint returns the endpoint new requests for the default project
+// are sent to.
+func (c *Client) ActiveEndpoint() string {
+	for _, s := range c.Endpoints() {
+		if s.Active {
+			return s.URL
+		}
+	}
+	return c.endpoint.String()
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestProjectOf(t *testing.T) {
+	u, _ := url.Parse("https://api.example.com/v1")
+	p := newEndpointPool([]*url.URL{u}, 0)
+	tests := []struct {
+		uri  string
+		want string
+	}{
+		{"https://api.example.com/v1/projects/p1", "p1"},
+		{"https://api.example.com/v1/projects/p1/mail/m1", "p1"},
+		{"https://api.example.com/v1/projects/p1?cursor=c", "p1"},
+		{"https://api.example.com/v1/projects/a%2Fb/mail", "a/b"},
+		{"https://api.example.com/v1/projects?userId=u1", ""},
+		{"https://api.example.com/v1/info", ""},
+		{"https://api.example.com/info", ""},
+		{"https://other.example.com/v1/projects/p1", ""},
+	}
+	for _, tc := range tests {
+		if got := p.projectOf(tc.uri); got != tc.want {
+			t.Errorf("projectOf(%s) = %q, want %q", tc.uri, got, tc.want)
+		}
+	}
+}
+
+func TestFailover(t *testing.T) {
+	primaryDown := true
+	var primaryHits, secondaryHits []string
+	mail := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"id":"m1","projectId":"p1","status":"delivered"}}`)
+	}
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryHits = append(primaryHits, r.URL.Path)
+		if primaryDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		mail(w, r)
+	}))
+	defer primary.Close()
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secondaryHits = append(secondaryHits, r.URL.Path)
+		mail(w, r)
+	}))
+	defer secondary.Close()
+
+	c, err := NewClientWithOptions(primary.URL+"/v1",
+		WithFailover(secondary.URL+"/v1"), WithEndpointCooldown(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	c.pool.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := c.GetMail(ctx, "p1", "m1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(primaryHits) != 1 || len(secondaryHits) != 1 {
+		t.Fatalf("primary %v, secondary %v, want one request each", primaryHits, secondaryHits)
+	}
+
+	eps := c.Endpoints()
+	if len(eps) != 2 {
+		t.Fatalf("Endpoints = %+v", eps)
+	}
+	if eps[0].Healthy || !eps[0].UnhealthyUntil.Equal(now.Add(time.Minute)) ||
+		eps[0].Failures != 1 || eps[0].Requests != 1 || eps[0].LastError == "" || eps[0].Active {
+		t.Errorf("primary status %+v", eps[0])
+	}
+	if !eps[1].Healthy || !eps[1].Active || eps[1].Requests != 1 || eps[1].Failures != 0 {
+		t.Errorf("secondary status %+v", eps[1])
+	}
+	if got := c.ActiveEndpoint(); got != secondary.URL+"/v1" {
+		t.Errorf("ActiveEndpoint = %s during cooldown, want the secondary", got)
+	}
+
+	// once the cooldown expires new projects use the primary again but p1
+	// stays on the secondary
+	primaryDown = false
+	now = now.Add(time.Minute + time.Second)
+	if eps := c.Endpoints(); !eps[0].Healthy || !eps[0].UnhealthyUntil.IsZero() {
+		t.Errorf("primary status %+v after the cooldown", eps[0])
+	}
+	if got := c.ActiveEndpoint(); got != primary.URL+"/v1" {
+		t.Errorf("ActiveEndpoint = %s after the cooldown, want the primary", got)
+	}
+	primaryHits, secondaryHits = nil, nil
+	if _, err := c.GetMail(ctx, "p1", "m1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetMail(ctx, "p2", "m1"); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(secondaryHits) != "[/v1/projects/p1/mail/m1]" {
+		t.Errorf("secondary received %v, want only p1", secondaryHits)
+	}
+	if fmt.Sprint(primaryHits) != "[/v1/projects/p2/mail/m1]" {
+		t.Errorf("primary received %v, want only p2", primaryHits)
+	}
+}
@@ -0,0 +1,311 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultFollowPollInterval = 2 * time.Second
+
+// followLookback is how far behind the cursor polls and reconnects ask for
+// logs, so that logs that reach the server late are still picked up.
+const followLookback = time.Minute
+
+// errStreamUnsupported the API does not offer a mail log event stream.
+var errStreamUnsupported = errors.New("mail log event stream not supported")
+
+// FollowOptions parameters for FollowMailLogs.
+type FollowOptions struct {
+	// MailID (optional) restricts the logs to a single mail entry. Left
+	// unset logs for every mail entry in the project are followed.
+	MailID string
+
+	// Since (optional) only logs created after Since are emitted. Left
+	// unset every existing log is emitted before new ones.
+	Since time.Time
+
+	// PollInterval is the delay between polls when the API does not
+	// offer an event stream. Left unset defaults to 2s.
+	PollInterval time.Duration
+
+	// DisableStream forces polling even if the API offers an event
+	// stream.
+	DisableStream bool
+
+	// Logs receives each new MailLog. FollowMailLogs closes the channel
+	// when it returns.
+	Logs chan<- MailLog
+}
+
+// FollowMailLogs emits mail logs on opts.Logs as they are created until ctx
+// is done. It uses the server-sent events stream at
+// projects/{id}/mail-logs/stream when the API offers one and falls back to
+// incremental polling otherwise. Failed polls are retried with backoff;
+// only API errors such as an unknown project end the follow. It returns
+// ctx.Err() when ctx is done.
+func (c *Client) FollowMailLogs(ctx context.Context, projectID string, opts FollowOptions) error {
+	if opts.Logs == nil {
+		return errors.New("follow mail logs: Logs channel is required")
+	}
+	defer close(opts.Logs)
+
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultFollowPollInterval
+	}
+
+	f := &follower{
+		c:         c,
+		projectID: projectID,
+		opts:      opts,
+		start:     time.Now(),
+		cursor:    opts.Since,
+		seen:      make(map[string]struct{}),
+	}
+
+	if !opts.DisableStream {
+		err := f.stream(ctx)
+		if err != errStreamUnsupported {
+			return err
+		}
+	}
+	return f.poll(ctx)
+}
+
+// follower holds the state shared by the stream and poll strategies so
+// that no log is emitted twice.
+type follower struct {
+	c         *Client
+	projectID string
+	opts      FollowOptions
+	start     time.Time // when the follow began
+
+	// cursor is the created time of the newest log emitted, or start once
+	// a poll has caught up with the logs created before the follow began
+	cursor time.Time
+	seen   map[string]struct{} // ids of every log emitted
+}
+
+// emit sends l on the logs channel unless it has already been sent or is
+// older than Since. Logs that arrive behind the cursor are still emitted.
+func (f *follower) emit(ctx context.Context, l MailLog) error {
+	if _, ok := f.seen[l.ID]; ok {
+		return nil
+	}
+	if !f.opts.Since.IsZero() && !l.CreatedAt.After(f.opts.Since) {
+		return nil
+	}
+	f.seen[l.ID] = struct{}{}
+	if l.CreatedAt.After(f.cursor) {
+		f.cursor = l.CreatedAt
+	}
+
+	select {
+	case f.opts.Logs <- l:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// since returns the time to ask the server for logs from, allowing for
+// logs that arrive late.
+func (f *follower) since() time.Time {
+	if f.cursor.IsZero() {
+		return f.cursor
+	}
+	since := f.cursor.Add(-followLookback)
+	if !f.opts.Since.IsZero() && since.Before(f.opts.Since) {
+		return f.opts.Since
+	}
+	return since
+}
+
+// stream consumes the server-sent events stream, reconnecting with
+// Last-Event-ID when the connection drops. It returns errStreamUnsupported
+// if the first connection attempt fails.
+func (f *follower) stream(ctx context.Context) error {
+	// the stream is long lived so must not be subject to the client timeout
+	hc := &http.Client{
+		Transport:     f.c.client.Transport,
+		CheckRedirect: f.c.client.CheckRedirect,
+		Jar:           f.c.client.Jar,
+	}
+
+	var lastEventID string
+	retry := f.opts.PollInterval
+	connected := false
+	for {
+		err := f.streamOnce(ctx, hc, &lastEventID, &retry, &connected)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, ok := err.(*APIError); ok {
+			return err
+		}
+		if err != nil && !connected {
+			// never managed to stream so let polling take over
+			return errStreamUnsupported
+		}
+
+		t := time.NewTimer(retry)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (f *follower) streamOnce(ctx context.Context, hc *http.Client, lastEventID *string, retry *time.Duration, connected *bool) error {
+	query := url.Values{}
+	if f.opts.MailID != "" {
+		query.Set("mailId", f.opts.MailID)
+	}
+	if since := f.since(); !since.IsZero() {
+		query.Set("since", since.UTC().Format(time.RFC3339Nano))
+	}
+	path, err := pathf("projects/%s/mail-logs/stream", f.c.project(f.projectID))
+	if err != nil {
+		return err
+	}
+	uri := f.c.buildURL(path, query)
+	ep := f.c.pool.pick(f.c.project(f.projectID))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.c.pool.rebase(uri.String(), ep), nil)
+	if err != nil {
+		return errors.Wrap(err, "new HTTP GET request")
+	}
+	f.c.setHeaders(req)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	if *lastEventID != "" {
+		req.Header.Set("Last-Event-ID", *lastEventID)
+	}
+
+	res, _, _, err := f.c.roundTrip(ctx, hc, req, ep)
+	if err != nil {
+		return errors.Wrap(err, "do HTTP GET request")
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusNotFound, http.StatusMethodNotAllowed,
+		http.StatusNotAcceptable, http.StatusNotImplemented:
+		return errStreamUnsupported
+	}
+	if res.StatusCode >= 400 && res.StatusCode < 500 {
+		return decodeAPIError(res)
+	}
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("mail log stream responded %s", res.Status)
+	}
+	if mt, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type")); mt != "text/event-stream" {
+		return errStreamUnsupported
+	}
+	*connected = true
+
+	r := newSSEReader(res.Body)
+	r.lastEventID = *lastEventID
+	for {
+		ev, err := r.next()
+		*lastEventID = r.lastEventID
+		if r.retry > 0 {
+			*retry = r.retry
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "read mail log stream")
+		}
+		if ev.Event != "" && ev.Event != "message" && ev.Event != "mail-log" {
+			continue
+		}
+
+		var l MailLog
+		if err := json.Unmarshal([]byte(ev.Data), &l); err != nil {
+			return errors.Wrap(err, "json decode mail log event")
+		}
+		if err := f.emit(ctx, l); err != nil {
+			return err
+		}
+	}
+}
+
+// poll repeatedly lists logs created since the cursor. Failed polls are
+// retried with a doubling delay unless the API rejected the request.
+func (f *follower) poll(ctx context.Context) error {
+	failures := 0
+	for {
+		var logs []MailLog
+		var err error
+		if f.opts.MailID != "" {
+			logs, err = f.c.ListMailLogs(ctx, f.projectID, f.opts.MailID)
+		} else {
+			logs, err = f.c.ListProjectMailLogs(ctx, f.projectID, f.since())
+		}
+		wait := f.opts.PollInterval
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil && !transientFollowError(err):
+			return errors.Wrap(err, "follow mail logs")
+		case err != nil:
+			wait = f.opts.PollInterval << uint(failures)
+			if wait <= 0 || wait > DefaultMaxRetryBackoff {
+				wait = DefaultMaxRetryBackoff
+			}
+			failures++
+			if f.c.logger != nil {
+				f.c.logger.Printf("raven: follow mail logs: %v, retrying in %s", err, wait)
+			}
+		default:
+			failures = 0
+			sort.SliceStable(logs, func(i, j int) bool {
+				return logs[i].CreatedAt.Before(logs[j].CreatedAt)
+			})
+			for _, l := range logs {
+				if err := f.emit(ctx, l); err != nil {
+					return err
+				}
+			}
+			// every log created before the follow began has now been
+			// listed, so later polls need not fetch them again
+			if f.cursor.Before(f.start) {
+				f.cursor = f.start
+			}
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// transientFollowError reports whether a failed poll should be retried.
+// Client errors from the API, such as an unknown project, and invalid
+// arguments are permanent.
+func transientFollowError(err error) bool {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Status >= 500 || apiErr.Status == http.StatusTooManyRequests
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func mailLogJSON(id string, created time.Time) string {
+	return fmt.Sprintf(`{"id":%q,"mailId":"m1","projectId":"p1","status":"sent","createdAt":%q}`,
+		id, created.UTC().Format(time.RFC3339Nano))
+}
+
+// collectLogs runs FollowMailLogs until n logs have been received, then
+// cancels it and returns the logs and its error.
+func collectLogs(t *testing.T, c *Client, opts FollowOptions, n int) ([]string, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	logs := make(chan MailLog)
+	opts.Logs = logs
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.FollowMailLogs(ctx, "p1", opts)
+	}()
+	var ids []string
+	for l := range logs {
+		ids = append(ids, l.ID)
+		if len(ids) == n {
+			cancel()
+		}
+	}
+	return ids, <-errc
+}
+
+func TestFollowMailLogsStream(t *testing.T) {
+	now := time.Now()
+	var mu sync.Mutex
+	var lastIDs []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/projects/p1/mail-logs/stream" {
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		n := len(lastIDs)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch n {
+		case 1:
+			// events without data only update the retry and id
+			fmt.Fprint(w, "retry: 10\n\n")
+			fmt.Fprintf(w, "id: e1\nevent: mail-log\ndata: %s\n\n", mailLogJSON("l1", now))
+			fmt.Fprint(w, "id: e2\n\n")
+		default:
+			fmt.Fprintf(w, "id: e3\ndata: %s\n\n", mailLogJSON("l1", now))
+			fmt.Fprintf(w, "event: ping\ndata: {}\n\n")
+			fmt.Fprintf(w, "id: e4\ndata: %s\n\n", mailLogJSON("l2", now))
+		}
+	})
+
+	ids, err := collectLogs(t, c, FollowOptions{PollInterval: time.Hour}, 2)
+	if err != context.Canceled {
+		t.Errorf("FollowMailLogs error = %v, want context.Canceled", err)
+	}
+	if fmt.Sprint(ids) != "[l1 l2]" {
+		t.Errorf("received %v, want [l1 l2]", ids)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(lastIDs) < 2 || lastIDs[0] != "" || lastIDs[1] != "e2" {
+		t.Errorf("Last-Event-ID headers %q, want [\"\" e2 ...]", lastIDs)
+	}
+}
+
+func TestFollowMailLogsPoll(t *testing.T) {
+	start := time.Now()
+	var mu sync.Mutex
+	var sinces []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/projects/p1/mail-logs/stream":
+			w.WriteHeader(http.StatusNotFound)
+			return
+		case "/v1/projects/p1/mail-logs":
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		mu.Lock()
+		sinces = append(sinces, r.URL.Query().Get("since"))
+		n := len(sinces)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch n {
+		case 1:
+			fmt.Fprintf(w, `{"data":[%s]}`, mailLogJSON("old", start.Add(-time.Hour)))
+		case 2:
+			fmt.Fprint(w, `{"data":[]}`)
+		case 3:
+			// a failed poll is retried
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"status":503,"code":"unavailable","message":"try again"}`)
+		default:
+			fmt.Fprintf(w, `{"data":[%s,%s]}`,
+				mailLogJSON("new", time.Now()), mailLogJSON("old", start.Add(-time.Hour)))
+		}
+	})
+
+	ids, err := collectLogs(t, c, FollowOptions{PollInterval: time.Millisecond}, 2)
+	if err != context.Canceled {
+		t.Errorf("FollowMailLogs error = %v, want context.Canceled", err)
+	}
+	if fmt.Sprint(ids) != "[old new]" {
+		t.Errorf("received %v, want [old new]", ids)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sinces) < 4 || sinces[0] != "" {
+		t.Fatalf("since queries %q, want every log on the first poll", sinces)
+	}
+	// once caught up polls only ask for logs since the follow began
+	for _, s := range sinces[1:] {
+		since, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			t.Fatalf("since %q: %v", s, err)
+		}
+		if since.Before(start.Add(-followLookback - time.Second)) {
+			t.Errorf("since %s is before the follow began at %s", since, start)
+		}
+	}
+}
+
+func TestFollowMailLogsAPIError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"status":404,"code":"project/not-found","message":"project not found"}`)
+	})
+
+	ids, err := collectLogs(t, c, FollowOptions{PollInterval: time.Millisecond}, 1)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "project/not-found" {
+		t.Errorf("FollowMailLogs error = %v, want project/not-found", err)
+	}
+	if len(ids) != 0 {
+		t.Errorf("received %v, want none", ids)
+	}
+}
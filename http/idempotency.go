@@ -0,0 +1,66 @@
+package http
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+)
+
+// IdempotencyKeyHeader is sent with every POST, PUT and PATCH request. The
+// server returns the original response for a repeated key instead of
+// repeating the action, so retries cannot create duplicates.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type idempotencyKeyCtxKey struct{}
+
+// WithIdempotencyKey returns a context that makes the client send key as
+// the Idempotency-Key of the next mutating request made with it. Use it
+// to supply your own key to methods whose params have no IdempotencyKey
+// field, e.g. CreateGroup. Reusing the context for a different request
+// makes the server treat it as a repeat of the first.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtxKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the key set by WithIdempotencyKey.
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyCtxKey{}).(string)
+	return key, ok && key != ""
+}
+
+// NewIdempotencyKey returns a random version 4 UUID.
+func NewIdempotencyKey() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("raven: read random idempotency key: %v", err))
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	h := hex.EncodeToString(b[:])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// isMutating reports whether requests with method change server state and
+// so carry an Idempotency-Key. DELETE is idempotent by definition, so
+// repeating it needs no key.
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return true
+	}
+	return false
+}
+
+// idempotencyKey returns the key for a request, taken from ctx or
+// generated.
+func idempotencyKey(ctx context.Context, method string) string {
+	if !isMutating(method) {
+		return ""
+	}
+	if key, ok := IdempotencyKeyFromContext(ctx); ok {
+		return key
+	}
+	return NewIdempotencyKey()
+}
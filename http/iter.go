@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+)
+
+// MailIterator walks every page of mail resources. Use it like a
+// bufio.Scanner:
+//
+//	it := client.IterateMail(ctx, projectID, http.ListMailParams{Since: since})
+//	for it.Next() {
+//		m := it.Mail()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+//
+// Only one page is held in memory at a time.
+type MailIterator struct {
+	c         *Client
+	ctx       context.Context
+	projectID string
+	params    ListMailParams
+
+	page []Mail
+	i    int
+	cur  Mail
+	done bool
+	err  error
+}
+
+// IterateMail returns an iterator over all mail matching params. The
+// Since, Until and Status filters are also applied client-side in case the
+// server ignores them.
+func (c *Client) IterateMail(ctx context.Context, projectID string, params ListMailParams) *MailIterator {
+	return &MailIterator{
+		c:         c,
+		ctx:       ctx,
+		projectID: projectID,
+		params:    params,
+	}
+}
+
+// Next advances to the next mail, fetching the next page when required.
+// It returns false when there is no more mail or an error occurred.
+func (it *MailIterator) Next() bool {
+	for {
+		for it.i < len(it.page) {
+			m := it.page[it.i]
+			it.i++
+			if it.match(m) {
+				it.cur = m
+				return true
+			}
+		}
+		if it.done || it.err != nil {
+			return false
+		}
+
+		page, err := it.c.ListMailPage(it.ctx, it.projectID, it.params)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.page, it.i = page.Data, 0
+		if page.NextCursor == "" || page.NextCursor == it.params.Cursor {
+			it.done = true
+		}
+		it.params.Cursor = page.NextCursor
+	}
+}
+
+// Mail returns the current mail.
+func (it *MailIterator) Mail() Mail {
+	return it.cur
+}
+
+// Err returns the first error encountered while fetching pages.
+func (it *MailIterator) Err() error {
+	return it.err
+}
+
+func (it *MailIterator) match(m Mail) bool {
+	if !it.params.Since.IsZero() && m.CreatedAt.Before(it.params.Since) {
+		return false
+	}
+	if !it.params.Until.IsZero() && !m.CreatedAt.Before(it.params.Until) {
+		return false
+	}
+	if it.params.Status != "" && m.Status != it.params.Status {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Response headers read into ResponseMeta.
+const (
+	RequestIDHeader          = "X-Request-Id"
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+	ServerVersionHeader      = "X-Raven-Version"
+)
+
+// ResponseMeta metadata from the headers of an API response. Quote the
+// RequestID in support requests.
+type ResponseMeta struct {
+	StatusCode int
+	Endpoint   string
+	RequestID  string
+
+	// RateLimitLimit and RateLimitRemaining are -1 if the server did not
+	// send them.
+	RateLimitLimit     int
+	RateLimitRemaining int
+	RateLimitReset     time.Time
+
+	ServerVersion string
+
+	// Deprecated is set if the server marked the endpoint as deprecated
+	// with a Deprecation header. Sunset is when it will be removed, if
+	// known.
+	Deprecated bool
+	Sunset     time.Time
+
+	// Warnings are the texts of any Warning headers.
+	Warnings []string
+}
+
+type responseMetaCtxKey struct{}
+
+// WithResponseMeta returns a context that makes the client fill in meta
+// from the response to the request made with it. If a call makes more than
+// one request, e.g. IterateMail following pages or a retried request, meta
+// describes the last.
+//
+//	var meta http.ResponseMeta
+//	mail, err := client.GetMail(http.WithResponseMeta(ctx, &meta), projectID, mailID)
+//	log.Printf("request %s, %d requests remaining", meta.RequestID, meta.RateLimitRemaining)
+func WithResponseMeta(ctx context.Context, meta *ResponseMeta) context.Context {
+	return context.WithValue(ctx, responseMetaCtxKey{}, meta)
+}
+
+// WithResponseHook calls fn with the metadata of every API response, e.g.
+// to log deprecation warnings or export rate limits as metrics. fn must
+// not block.
+func WithResponseHook(fn func(ResponseMeta)) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return errors.New("response hook must not be nil")
+		}
+		o.responseHook = fn
+		return nil
+	}
+}
+
+// newResponseMeta reads the metadata headers of res.
+func newResponseMeta(res *http.Response) ResponseMeta {
+	h := res.Header
+	m := ResponseMeta{
+		StatusCode:         res.StatusCode,
+		RequestID:          h.Get(RequestIDHeader),
+		RateLimitLimit:     headerInt(h, RateLimitLimitHeader),
+		RateLimitRemaining: headerInt(h, RateLimitRemainingHeader),
+		ServerVersion:      h.Get(ServerVersionHeader),
+		Warnings:           h.Values("Warning"),
+	}
+	if res.Request != nil {
+		m.Endpoint = res.Request.URL.Scheme + "://" + res.Request.URL.Host
+	}
+	if v := h.Get(RateLimitResetHeader); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			// either a unix time or a number of seconds from now
+			if n > 1e9 {
+				m.RateLimitReset = time.Unix(n, 0)
+			} else {
+				m.RateLimitReset = time.Now().Add(time.Duration(n) * time.Second)
+			}
+		}
+	}
+	if v := h.Get("Deprecation"); v != "" && !strings.EqualFold(v, "false") {
+		m.Deprecated = true
+	}
+	if v := h.Get("Sunset"); v != "" {
+		if t, err := http.ParseTime(v); err == nil {
+			m.Sunset = t
+		}
+	}
+	return m
+}
+
+func headerInt(h http.Header, key string) int {
+	n, err := strconv.Atoi(h.Get(key))
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// recordMeta passes the metadata of res to the context out-parameter and
+// response hook, if any.
+func (c *Client) recordMeta(ctx context.Context, res *http.Response) {
+	out, _ := ctx.Value(responseMetaCtxKey{}).(*ResponseMeta)
+	if out == nil && c.responseHook == nil {
+		return
+	}
+	m := newResponseMeta(res)
+	if out != nil {
+		*out = m
+	}
+	if c.responseHook != nil {
+		c.responseHook(m)
+	}
+}
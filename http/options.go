@@ -0,0 +1,304 @@
+package http
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultUserAgent sent with every request unless overridden with
+// WithUserAgent.
+const DefaultUserAgent = "raven-client-go"
+
+// Default retry settings used by WithRetries when backoff is zero.
+const (
+	DefaultRetryBackoff    = 250 * time.Millisecond
+	DefaultMaxRetryBackoff = 10 * time.Second
+)
+
+// Logger receives one line per request and retry. *log.Logger satisfies
+// this interface.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Option configures a Client. Options that are given invalid values cause
+// NewClientWithOptions to return an error.
+type Option func(*options) error
+
+// options collects the Option values before the Client is assembled.
+type options struct {
+	httpClient *http.Client
+	transport  http.RoundTripper
+	timeout    time.Duration
+	timeoutSet bool
+
+	userAgent string
+	projectID string
+	auth      func(*http.Request)
+	headers   http.Header
+
+	retries      int
+	retryBackoff time.Duration
+
+	logger  Logger
+	breaker *CircuitBreaker
+	cache   Cache
+	strict  bool
+
+	tlsConfig  *tls.Config
+	proxy      func(*http.Request) (*url.URL, error)
+	unixSocket string
+
+	debug io.Writer
+
+	failover []*url.URL
+	cooldown time.Duration
+
+	responseHook func(ResponseMeta)
+}
+
+// WithHTTPClient uses hc for all requests instead of a client built by
+// NewClientWithOptions. It cannot be combined with WithTransport. The TLS,
+// proxy and socket options are applied to a copy of hc if its transport is
+// an *http.Transport.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(o *options) error {
+		if hc == nil {
+			return errors.New("http client must not be nil")
+		}
+		o.httpClient = hc
+		return nil
+	}
+}
+
+// WithTransport uses rt as the transport of the client built by
+// NewClientWithOptions. Left unset an *http.Transport with
+// MaxIdleConnsPerHost of 10 is used. The TLS, proxy and socket options
+// require rt to be an *http.Transport.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *options) error {
+		if rt == nil {
+			return errors.New("transport must not be nil")
+		}
+		o.transport = rt
+		return nil
+	}
+}
+
+// WithTimeout limits the time taken by each request including reading the
+// response body. Zero means no timeout. Left unset defaults to
+// DefaultTimout.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		if d < 0 {
+			return errors.Errorf("timeout %s must not be negative", d)
+		}
+		o.timeout = d
+		o.timeoutSet = true
+		return nil
+	}
+}
+
+// WithUserAgent sets the User-Agent header. Left unset defaults to
+// DefaultUserAgent.
+func WithUserAgent(ua string) Option {
+	return func(o *options) error {
+		if strings.TrimSpace(ua) == "" {
+			return errors.New("user agent must not be empty")
+		}
+		if strings.ContainsAny(ua, "\r\n") {
+			return errors.New("user agent must not contain CR or LF")
+		}
+		o.userAgent = ua
+		return nil
+	}
+}
+
+// WithProjectID sets the project used by methods that are passed an empty
+// project ID.
+func WithProjectID(projectID string) Option {
+	return func(o *options) error {
+		if projectID == "" {
+			return errors.New("default project ID must not be empty")
+		}
+		o.projectID = projectID
+		return nil
+	}
+}
+
+// WithBearerToken authenticates every request with an Authorization:
+// Bearer header.
+func WithBearerToken(token string) Option {
+	return func(o *options) error {
+		if token == "" {
+			return errors.New("bearer token must not be empty")
+		}
+		if strings.ContainsAny(token, "\r\n") {
+			return errors.New("bearer token must not contain CR or LF")
+		}
+		o.auth = func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return nil
+	}
+}
+
+// WithBasicAuth authenticates every request with HTTP basic auth.
+func WithBasicAuth(username, password string) Option {
+	return func(o *options) error {
+		if username == "" {
+			return errors.New("basic auth username must not be empty")
+		}
+		o.auth = func(req *http.Request) {
+			req.SetBasicAuth(username, password)
+		}
+		return nil
+	}
+}
+
+// WithHeader adds a header sent with every request. Headers set by the
+// client itself, such as Accept and Authorization, take precedence.
+func WithHeader(key, value string) Option {
+	return func(o *options) error {
+		if key == "" || strings.ContainsAny(key, " :\r\n") {
+			return errors.Errorf("invalid header name %q", key)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return errors.Errorf("header %s value must not contain CR or LF", key)
+		}
+		if o.headers == nil {
+			o.headers = make(http.Header)
+		}
+		o.headers.Add(key, value)
+		return nil
+	}
+}
+
+// WithRetries retries idempotent requests (GET, HEAD, PUT and DELETE), and
+// POST and PATCH requests carrying an Idempotency-Key, up to max times when
+// the request fails to reach the server or the server responds 429, 502,
+// 503 or 504. Every POST and PATCH is sent with a key, generated if not
+// set with WithIdempotencyKey, so in practice these are retried too. The
+// wait doubles after each attempt starting at backoff, capped at
+// DefaultMaxRetryBackoff, and honours a Retry-After header. A zero backoff
+// defaults to DefaultRetryBackoff.
+func WithRetries(max int, backoff time.Duration) Option {
+	return func(o *options) error {
+		if max < 0 {
+			return errors.Errorf("retries %d must not be negative", max)
+		}
+		if backoff < 0 {
+			return errors.Errorf("retry backoff %s must not be negative", backoff)
+		}
+		if backoff == 0 {
+			backoff = DefaultRetryBackoff
+		}
+		o.retries = max
+		o.retryBackoff = backoff
+		return nil
+	}
+}
+
+// WithLogger logs each request and retry to l.
+func WithLogger(l Logger) Option {
+	return func(o *options) error {
+		if l == nil {
+			return errors.New("logger must not be nil")
+		}
+		o.logger = l
+		return nil
+	}
+}
+
+// WithCircuitBreaker fails requests fast with ErrCircuitOpen while the API
+// is unavailable.
+func WithCircuitBreaker(cb *CircuitBreaker) Option {
+	return func(o *options) error {
+		if cb == nil {
+			return errors.New("circuit breaker must not be nil")
+		}
+		o.breaker = cb
+		return nil
+	}
+}
+
+// WithCache stores GET responses that carry an ETag. See Config.Cache.
+func WithCache(cache Cache) Option {
+	return func(o *options) error {
+		if cache == nil {
+			return errors.New("cache must not be nil")
+		}
+		o.cache = cache
+		return nil
+	}
+}
+
+// WithStrictDecoding rejects responses containing unknown fields with a
+// *SchemaDriftError. See Config.StrictDecoding.
+func WithStrictDecoding() Option {
+	return func(o *options) error {
+		o.strict = true
+		return nil
+	}
+}
+
+// WithConfig applies the settings of a Config, for callers migrating from
+// NewClient. Config.Endpoint is ignored; pass it to NewClientWithOptions.
+// Config.Endpoints are added as fallbacks. Only fields that are set are
+// applied, so they do not undo options given earlier.
+func WithConfig(c Config) Option {
+	return func(o *options) error {
+		if c.Timeout != 0 {
+			if err := WithTimeout(c.Timeout)(o); err != nil {
+				return err
+			}
+		}
+		if len(c.Endpoints) > 0 {
+			if err := WithFailover(c.Endpoints...)(o); err != nil {
+				return err
+			}
+		}
+		if c.EndpointCooldown != 0 {
+			if err := WithEndpointCooldown(c.EndpointCooldown)(o); err != nil {
+				return err
+			}
+		}
+		if c.CircuitBreaker != nil {
+			o.breaker = c.CircuitBreaker
+		}
+		if c.Cache != nil {
+			o.cache = c.Cache
+		}
+		if c.StrictDecoding {
+			o.strict = true
+		}
+		var opts []Option
+		if c.CACertFile != "" {
+			opts = append(opts, WithRootCAFile(c.CACertFile))
+		}
+		if c.CertFile != "" || c.KeyFile != "" {
+			opts = append(opts, WithClientCertFiles(c.CertFile, c.KeyFile))
+		}
+		if c.MinTLSVersion != 0 {
+			opts = append(opts, WithMinTLSVersion(c.MinTLSVersion))
+		}
+		if c.Proxy != "" {
+			opts = append(opts, WithProxy(c.Proxy))
+		}
+		if c.UnixSocket != "" {
+			opts = append(opts, WithUnixSocket(c.UnixSocket))
+		}
+		for _, opt := range opts {
+			if err := opt(o); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RetryFilter selects the mail entries resent by RetryFailed.
+type RetryFilter struct {
+	// Status of mail to retry. Left unset defaults to MailStatusFailed.
+	Status MailStatus
+
+	// Since (optional) only retries mail created after Since.
+	Since time.Time
+
+	// TemplateID (optional) only retries mail sent with this template.
+	TemplateID string
+
+	// TransportID (optional) resends using this transport instead of the
+	// project's active transport.
+	TransportID string
+
+	// DryRun selects the matching mail without resending it.
+	DryRun bool
+}
+
+// RetryResult outcome of retrying a single mail entry. Resent is nil for a
+// dry run or if Err is set.
+type RetryResult struct {
+	Mail   Mail
+	Resent *Mail
+	Err    error
+}
+
+// RetryFailed walks every page of the project's mail and resends every
+// entry matching filter. The matching mail is listed in full before any is
+// resent, so that resent entries changing status cannot shift the pages. A
+// failure to resend one entry is recorded in its RetryResult and does not
+// stop the others; an error is only returned if the mail cannot be listed
+// or ctx is done.
+func (c *Client) RetryFailed(ctx context.Context, projectID string, filter RetryFilter) ([]RetryResult, error) {
+	if filter.Status == "" {
+		filter.Status = MailStatusFailed
+	}
+
+	var mail []Mail
+	it := c.IterateMail(ctx, projectID, ListMailParams{
+		Since:  filter.Since,
+		Status: filter.Status,
+	})
+	for it.Next() {
+		m := it.Mail()
+		if filter.TemplateID != "" && m.TemplateID != filter.TemplateID {
+			continue
+		}
+		mail = append(mail, m)
+	}
+	if err := it.Err(); err != nil {
+		return nil, errors.Wrap(err, "retry failed mail")
+	}
+
+	var results []RetryResult
+	for _, m := range mail {
+		r := RetryResult{Mail: m}
+		if !filter.DryRun {
+			r.Resent, r.Err = c.ResendMail(ctx, projectID, m.ID, ResendOptions{
+				TransportID: filter.TransportID,
+			})
+			if ctx.Err() != nil {
+				return results, ctx.Err()
+			}
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryFailedPages(t *testing.T) {
+	mail := func(id, status, created string) string {
+		return fmt.Sprintf(`{"id":%q,"templateId":"welcome","projectId":"p1","status":%q,"createdAt":%q}`,
+			id, status, created)
+	}
+	var queries []string
+	var resent []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/projects/p1/mail":
+			queries = append(queries, r.URL.RawQuery)
+			if r.URL.Query().Get("cursor") == "" {
+				fmt.Fprintf(w, `{"data":[%s,%s],"nextCursor":"page2"}`,
+					mail("m1", "failed", "2026-10-02T00:00:00Z"),
+					mail("m2", "sent", "2026-10-02T00:00:00Z"))
+				return
+			}
+			fmt.Fprintf(w, `{"data":[%s,%s]}`,
+				mail("m3", "failed", "2026-10-03T00:00:00Z"),
+				mail("m4", "failed", "2026-09-01T00:00:00Z"))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/resend"):
+			id := strings.Split(r.URL.Path, "/")[5]
+			resent = append(resent, id)
+			fmt.Fprintf(w, `{"data":%s}`, mail(id, "pending", "2026-10-02T00:00:00Z"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	results, err := c.RetryFailed(context.Background(), "p1", RetryFilter{Since: since})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(resent, ","); got != "m1,m3" {
+		t.Errorf("resent %s, want m1,m3", got)
+	}
+	if len(results) != 2 || results[1].Resent == nil || results[1].Resent.ID != "m3" {
+		t.Errorf("results = %+v", results)
+	}
+	if len(queries) != 2 {
+		t.Fatalf("listed %d pages, want 2", len(queries))
+	}
+	for _, q := range queries {
+		if !strings.Contains(q, "status=failed") || !strings.Contains(q, "since=2026-10-01T00%3A00%3A00Z") {
+			t.Errorf("list query %q does not filter by status and since", q)
+		}
+	}
+}
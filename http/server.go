@@ -0,0 +1,219 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SupportedAPIVersions API versions this client implements, oldest first.
+var SupportedAPIVersions = []string{"v1"}
+
+// legacyAPIVersions API versions served by servers that predate the info
+// endpoint.
+var legacyAPIVersions = []string{"v1"}
+
+// ServerInfo version and capabilities of a Raven Mailer server.
+type ServerInfo struct {
+	// Version of the server software, e.g. 1.14.2.
+	Version string `json:"version"`
+
+	// APIVersions served, e.g. v1 and v2.
+	APIVersions []string `json:"apiVersions"`
+
+	// Features flags enabled on the server.
+	Features map[string]bool `json:"features"`
+
+	// Legacy is true if the server has no info endpoint, i.e. it answered
+	// the info request with 404 Not Found and an X-Raven-Version header.
+	// Version is then taken from the header and APIVersions is v1, the
+	// only version served before the info endpoint was added.
+	Legacy bool `json:"-"`
+
+	// NewerAPIVersion is set by CheckCompatibility to the newest API
+	// version the server serves if it is newer than the client's. The
+	// client still works but should be upgraded before the server drops
+	// its version.
+	NewerAPIVersion string `json:"-"`
+}
+
+// HasFeature reports whether the server has the named feature enabled.
+func (s *ServerInfo) HasFeature(name string) bool {
+	return s.Features[name]
+}
+
+// ServerInfo fetches the version and capabilities of the server from the
+// info endpoint at the API root, e.g. https://api.ravenmailer.com/info for
+// an endpoint of https://api.ravenmailer.com/v1.
+func (c *Client) ServerInfo(ctx context.Context) (*ServerInfo, error) {
+	uri := c.rootURL("info")
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
+	// servers before the info endpoint was added. The version header shows
+	// the 404 came from a Raven server rather than e.g. a misconfigured
+	// endpoint or a proxy.
+	if terr, ok := err.(*APIError); ok && terr.Status == http.StatusNotFound &&
+		terr.header.Get(ServerVersionHeader) != "" {
+		return &ServerInfo{
+			Version:     terr.header.Get(ServerVersionHeader),
+			APIVersions: append([]string(nil), legacyAPIVersions...),
+			Legacy:      true,
+		}, nil
+	}
+	if err != nil {
+		return nil, requestError(err, "http get request failed")
+	}
+	defer res.Body.Close()
+
+	var container struct {
+		Data *ServerInfo `json:"data"`
+	}
+	if err := c.decode(res.Body, &container); err != nil {
+		return nil, errors.Wrapf(err, "json decode server info")
+	}
+	if container.Data == nil {
+		return nil, errors.New("server info response has no data")
+	}
+	return container.Data, nil
+}
+
+// APIVersion returns the API version the client talks to, taken from the
+// last path segment of the endpoint, e.g. v1 for
+// https://api.ravenmailer.com/v1. An endpoint without a version segment
+// defaults to the newest supported version.
+func (c *Client) APIVersion() string {
+	v := path.Base(strings.TrimSuffix(c.endpoint.Path, "/"))
+	if _, ok := parseAPIVersion(v); ok {
+		return v
+	}
+	return SupportedAPIVersions[len(SupportedAPIVersions)-1]
+}
+
+// rootURL resolves path against the API root for endpoints that are not
+// versioned, so an endpoint of https://api.ravenmailer.com/v1 and a path of
+// info give https://api.ravenmailer.com/info.
+func (c *Client) rootURL(path string) *url.URL {
+	return apiRoot(c.endpoint).ResolveReference(&url.URL{Path: path})
+}
+
+// apiRoot returns u without its query and trailing API version segment,
+// with a trailing slash.
+func apiRoot(u *url.URL) *url.URL {
+	root := *u
+	root.RawQuery = ""
+	root.Fragment = ""
+	p := strings.TrimSuffix(root.EscapedPath(), "/")
+	if i := strings.LastIndex(p, "/"); i >= 0 {
+		if _, ok := parseAPIVersion(p[i+1:]); ok {
+			p = p[:i]
+		}
+	}
+	root.RawPath = p + "/"
+	root.Path, _ = url.PathUnescape(root.RawPath)
+	return &root
+}
+
+// CompatibilityError is returned by CheckCompatibility when the client and
+// server have no API version in common.
+type CompatibilityError struct {
+	ClientVersion string   // API version the client uses
+	ServerVersion string   // server software version, if known
+	APIVersions   []string // API versions served
+
+	// ServerNewer is true if every version the server serves is newer
+	// than the client's, meaning the client needs an upgrade.
+	ServerNewer bool
+}
+
+// Error string representation of a CompatibilityError.
+func (e *CompatibilityError) Error() string {
+	server := "server"
+	if e.ServerVersion != "" {
+		server += " " + e.ServerVersion
+	}
+	if e.ServerNewer {
+		return fmt.Sprintf("%s serves API %s but the client uses %s: the server is newer, upgrade the client",
+			server, strings.Join(e.APIVersions, ", "), e.ClientVersion)
+	}
+	return fmt.Sprintf("%s serves API %s but the client uses %s: the server is older, use an older client or endpoint",
+		server, strings.Join(e.APIVersions, ", "), e.ClientVersion)
+}
+
+// CheckCompatibility fetches the server info and checks that the server
+// serves the client's API version. A mismatch is returned as a
+// *CompatibilityError together with the info. Legacy servers are taken to
+// serve v1 only. If the server also serves a newer version than the
+// client's, info.NewerAPIVersion is set.
+func (c *Client) CheckCompatibility(ctx context.Context) (*ServerInfo, error) {
+	info, err := c.ServerInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v := c.APIVersion()
+	n, _ := parseAPIVersion(v)
+	sorted := sortAPIVersions(info.APIVersions)
+	for _, s := range info.APIVersions {
+		if s == v {
+			if newest, m := newestAPIVersion(sorted); m > n {
+				info.NewerAPIVersion = newest
+			}
+			return info, nil
+		}
+	}
+
+	cerr := &CompatibilityError{
+		ClientVersion: v,
+		ServerVersion: info.Version,
+		APIVersions:   sorted,
+	}
+	if len(cerr.APIVersions) > 0 {
+		if oldest, ok := parseAPIVersion(cerr.APIVersions[0]); ok && oldest > n {
+			cerr.ServerNewer = true
+		}
+	}
+	return info, cerr
+}
+
+// parseAPIVersion parses an API version such as v2.
+func parseAPIVersion(s string) (int, bool) {
+	if !strings.HasPrefix(s, "v") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[1:])
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
+
+// newestAPIVersion returns the newest version of sorted, as returned by
+// sortAPIVersions, and its number, or 0 if none can be parsed.
+func newestAPIVersion(sorted []string) (string, int) {
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if n, ok := parseAPIVersion(sorted[i]); ok {
+			return sorted[i], n
+		}
+	}
+	return "", 0
+}
+
+// sortAPIVersions returns a copy of versions oldest first, with versions
+// that cannot be parsed last.
+func sortAPIVersions(versions []string) []string {
+	out := append([]string(nil), versions...)
+	sort.SliceStable(out, func(i, j int) bool {
+		a, aok := parseAPIVersion(out[i])
+		b, bok := parseAPIVersion(out[j])
+		if aok != bok {
+			return aok
+		}
+		return a < b
+	})
+	return out
+}
@@ -0,0 +1,150 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestServerInfoURL(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"https://api.example.com/v1", "https://api.example.com/info"},
+		{"https://api.example.com/v1/", "https://api.example.com/info"},
+		{"https://api.example.com", "https://api.example.com/info"},
+		{"https://example.com/raven/v2", "https://example.com/raven/info"},
+		{"https://example.com/raven", "https://example.com/raven/info"},
+		{"http://[::1]:8080/v1", "http://[::1]:8080/info"},
+	}
+	for _, tc := range tests {
+		c, err := NewClientWithOptions(tc.endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.rootURL("info").String(); got != tc.want {
+			t.Errorf("%s: info URL %s, want %s", tc.endpoint, got, tc.want)
+		}
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantLegacy  bool
+		wantNewer   string
+		wantErr     bool
+		serverNewer bool
+	}{
+		{"same version", 200, `{"data":{"version":"1.14.2","apiVersions":["v1"]}}`,
+			false, "", false, false},
+		{"server also serves newer", 200, `{"data":{"version":"2.0.0","apiVersions":["v2","v1"]}}`,
+			false, "v2", false, false},
+		{"server dropped client version", 200, `{"data":{"version":"3.0.0","apiVersions":["v2","v3"]}}`,
+			false, "", true, true},
+		{"legacy server", 404, `{"status":404,"code":"not-found","message":"not found"}`,
+			true, "", false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var paths []string
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				paths = append(paths, r.URL.Path)
+				w.Header().Set(ServerVersionHeader, "1.2.0")
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			})
+
+			info, err := c.CheckCompatibility(context.Background())
+			if !reflect.DeepEqual(paths, []string{"/info"}) {
+				t.Errorf("requested %v, want [/info]", paths)
+			}
+			if info == nil {
+				t.Fatalf("CheckCompatibility returned no info, error %v", err)
+			}
+			cerr, isCompat := err.(*CompatibilityError)
+			if tc.wantErr != isCompat || (err != nil && !isCompat) {
+				t.Fatalf("CheckCompatibility error = %v, want CompatibilityError %v", err, tc.wantErr)
+			}
+			if isCompat && cerr.ServerNewer != tc.serverNewer {
+				t.Errorf("ServerNewer = %v, want %v", cerr.ServerNewer, tc.serverNewer)
+			}
+			if info.Legacy != tc.wantLegacy {
+				t.Errorf("Legacy = %v, want %v", info.Legacy, tc.wantLegacy)
+			}
+			if info.NewerAPIVersion != tc.wantNewer {
+				t.Errorf("NewerAPIVersion = %q, want %q", info.NewerAPIVersion, tc.wantNewer)
+			}
+			if tc.wantLegacy && info.Version != "1.2.0" {
+				t.Errorf("legacy Version = %q, want 1.2.0 from the header", info.Version)
+			}
+		})
+	}
+}
+
+func TestCheckCompatibilityLegacyNewClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ServerVersionHeader, "1.2.0")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	// a client using v2 cannot talk to a server without the info endpoint
+	c, err := NewClientWithOptions(srv.URL + "/v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := c.CheckCompatibility(context.Background())
+	if _, ok := err.(*CompatibilityError); !ok {
+		t.Fatalf("CheckCompatibility error = %v, want *CompatibilityError", err)
+	}
+	if info == nil || !info.Legacy {
+		t.Errorf("info = %+v, want legacy", info)
+	}
+}
+
+func TestServerInfoFailover(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	var got string
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+		fmt.Fprint(w, `{"data":{"version":"1.14.2","apiVersions":["v1"]}}`)
+	}))
+	defer secondary.Close()
+
+	c, err := NewClientWithOptions(primary.URL+"/v1", WithFailover(secondary.URL+"/raven/v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ServerInfo(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got != "/raven/info" {
+		t.Errorf("failover requested %s, want /raven/info", got)
+	}
+}
+
+func TestServerInfoNotFoundWithoutVersion(t *testing.T) {
+	// a 404 without the version header is not from a Raven server, e.g.
+	// the endpoint points at the wrong host
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	info, err := c.ServerInfo(context.Background())
+	if info != nil {
+		t.Errorf("ServerInfo = %+v, want nil", info)
+	}
+	if apiErr, ok := err.(*APIError); !ok || apiErr.Status != http.StatusNotFound {
+		t.Errorf("ServerInfo error = %v, want 404 *APIError", err)
+	}
+}
@@ -0,0 +1,294 @@
+package http
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnhancedCode an RFC 3463 enhanced mail system status code such as 5.1.1.
+// The zero value means no enhanced code was present.
+type EnhancedCode struct {
+	Class   int // 2 success, 4 persistent transient failure, 5 permanent failure
+	Subject int
+	Detail  int
+}
+
+// String representation of an EnhancedCode, or an empty string for the zero
+// value.
+func (c EnhancedCode) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.%d", c.Class, c.Subject, c.Detail)
+}
+
+// IsZero reports whether c is the zero value.
+func (c EnhancedCode) IsZero() bool {
+	return c == EnhancedCode{}
+}
+
+// ParseEnhancedCode parses an enhanced status code in class.subject.detail
+// form.
+func ParseEnhancedCode(s string) (EnhancedCode, bool) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return EnhancedCode{}, false
+	}
+	var n [3]int
+	for i, p := range parts {
+		if p == "" || len(p) > 3 {
+			return EnhancedCode{}, false
+		}
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 {
+			return EnhancedCode{}, false
+		}
+		n[i] = v
+	}
+	if n[0] != 2 && n[0] != 4 && n[0] != 5 {
+		return EnhancedCode{}, false
+	}
+	return EnhancedCode{Class: n[0], Subject: n[1], Detail: n[2]}, true
+}
+
+// SMTPReply a reply from a remote MTA.
+type SMTPReply struct {
+	Code     int
+	Enhanced EnhancedCode
+	Text     string
+}
+
+// ParseSMTPReply parses the reply text of a remote MTA. Leading reply codes
+// are stripped from msg, including those repeated on each line of a
+// multiline reply ("550-5.1.1 ..."). The reply code is code; the code at
+// the start of msg is only used if code is zero.
+func ParseSMTPReply(code int, msg string) SMTPReply {
+	r := SMTPReply{Code: code}
+
+	var text []string
+	for _, line := range strings.Split(strings.TrimSpace(msg), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) >= 3 {
+			if n, err := strconv.Atoi(line[:3]); err == nil && n >= 200 && n < 600 &&
+				(len(line) == 3 || line[3] == ' ' || line[3] == '-') {
+				if r.Code == 0 {
+					r.Code = n
+				}
+				line = strings.TrimSpace(line[3:])
+				line = strings.TrimPrefix(line, "-")
+			}
+		}
+		if f := strings.Fields(line); len(f) > 0 {
+			if ec, ok := ParseEnhancedCode(f[0]); ok {
+				if r.Enhanced.IsZero() {
+					r.Enhanced = ec
+				}
+				line = strings.TrimSpace(line[len(f[0]):])
+			}
+		}
+		if line != "" {
+			text = append(text, line)
+		}
+	}
+	r.Text = strings.Join(text, " ")
+	return r
+}
+
+// String representation of an SMTPReply.
+func (r SMTPReply) String() string {
+	s := make([]string, 0, 3)
+	if r.Code != 0 {
+		s = append(s, strconv.Itoa(r.Code))
+	}
+	if !r.Enhanced.IsZero() {
+		s = append(s, r.Enhanced.String())
+	}
+	if r.Text != "" {
+		s = append(s, r.Text)
+	}
+	return strings.Join(s, " ")
+}
+
+// IsSuccess reports whether the reply is a 2xx success.
+func (r SMTPReply) IsSuccess() bool {
+	return r.Code >= 200 && r.Code < 300
+}
+
+// IsTemporary reports whether the reply is a 4xx transient failure.
+func (r SMTPReply) IsTemporary() bool {
+	if !r.Enhanced.IsZero() {
+		return r.Enhanced.Class == 4
+	}
+	return r.Code >= 400 && r.Code < 500
+}
+
+// IsPermanent reports whether the reply is a 5xx permanent failure.
+func (r SMTPReply) IsPermanent() bool {
+	if !r.Enhanced.IsZero() {
+		return r.Enhanced.Class == 5
+	}
+	return r.Code >= 500 && r.Code < 600
+}
+
+// Bounce classification of a failed delivery attempt.
+type Bounce string
+
+const (
+	// BounceNone the attempt did not fail, or the log has no SMTP reply.
+	BounceNone Bounce = ""
+
+	// BounceHard the address does not exist or will never accept mail.
+	// Further mail to it should be suppressed.
+	BounceHard Bounce = "hard"
+
+	// BounceSoft the mail was permanently rejected for a reason that may
+	// clear up, such as a full mailbox or an oversized message.
+	BounceSoft Bounce = "soft"
+
+	// BounceDeferral the remote MTA asked to try again later.
+	BounceDeferral Bounce = "deferral"
+
+	// BouncePolicy the mail was blocked by the remote MTA's security or
+	// spam policy.
+	BouncePolicy Bounce = "policy"
+)
+
+// policyPhrases reply text that indicates a policy block from MTAs which do
+// not send X.7.X enhanced codes.
+var policyPhrases = []string{
+	"blacklist", "blocklist", "blocked", "spam", "spf", "dmarc", "dkim", "reputation", "policy",
+}
+
+// Classify reports the kind of bounce the reply represents.
+func (r SMTPReply) Classify() Bounce {
+	switch {
+	case r.IsTemporary():
+		return BounceDeferral
+	case !r.IsPermanent():
+		return BounceNone
+	}
+
+	if !r.Enhanced.IsZero() {
+		switch r.Enhanced.Subject {
+		case 1: // addressing status
+			return BounceHard
+		case 2: // mailbox status
+			if r.Enhanced.Detail == 1 {
+				return BounceHard // mailbox disabled
+			}
+			return BounceSoft
+		case 3: // mail system status
+			return BounceSoft
+		case 7: // security or policy status
+			return BouncePolicy
+		}
+	}
+
+	text := strings.ToLower(r.Text)
+	for _, p := range policyPhrases {
+		if strings.Contains(text, p) {
+			return BouncePolicy
+		}
+	}
+	if r.Code == 552 {
+		// exceeded storage allocation
+		return BounceSoft
+	}
+	if r.Enhanced.IsZero() || r.Enhanced.Subject == 0 {
+		return BounceHard
+	}
+	return BounceSoft
+}
+
+// SMTPReply returns the parsed reply of the remote MTA for this log.
+func (l MailLog) SMTPReply() SMTPReply {
+	return ParseSMTPReply(l.SMTPCode, l.Msg)
+}
+
+// Bounce classifies the log's SMTP reply. Logs for mail that has not
+// failed or been deferred return BounceNone.
+func (l MailLog) Bounce() Bounce {
+	if l.SMTPCode == 0 && l.Status != MailStatusFailed {
+		return BounceNone
+	}
+	b := l.SMTPReply().Classify()
+	if b == BounceDeferral && l.Status == MailStatusFailed {
+		// the sender gave up retrying
+		return BounceSoft
+	}
+	return b
+}
+
+// RemoteMTA returns the host name of the remote MTA from Data, or an empty
+// string if not recorded.
+func (l MailLog) RemoteMTA() string {
+	return l.dataString("remoteMta", "mx", "host")
+}
+
+// RemoteIP returns the IP address of the remote MTA from Data, or an empty
+// string if not recorded.
+func (l MailLog) RemoteIP() string {
+	return l.dataString("remoteIp", "ip")
+}
+
+// QueueID returns the queue ID assigned by the remote MTA from Data, or an
+// empty string if not recorded.
+func (l MailLog) QueueID() string {
+	if id := l.dataString("queueId"); id != "" {
+		return id
+	}
+	// Postfix and others: "250 2.0.0 Ok: queued as 4Jx2kQ0Qz3z9sT"
+	if l.SMTPCode >= 200 && l.SMTPCode < 300 {
+		const marker = "queued as "
+		if i := strings.Index(l.Msg, marker); i >= 0 {
+			if f := strings.Fields(l.Msg[i+len(marker):]); len(f) > 0 {
+				return f[0]
+			}
+		}
+	}
+	return ""
+}
+
+// Attempt returns the delivery attempt number from Data, or zero if not
+// recorded.
+func (l MailLog) Attempt() int {
+	n, _ := l.dataNumber("attempt")
+	return int(n)
+}
+
+// Duration returns how long the SMTP transaction took from Data.
+func (l MailLog) Duration() (time.Duration, bool) {
+	ms, ok := l.dataNumber("durationMs", "elapsedMs")
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(ms * float64(time.Millisecond)), true
+}
+
+// dataString returns the first of keys present in Data as a string.
+func (l MailLog) dataString(keys ...string) string {
+	for _, k := range keys {
+		if v, ok := l.Data[k].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// dataNumber returns the first of keys present in Data as a number. JSON
+// numbers decode as float64; numeric strings are also accepted.
+func (l MailLog) dataNumber(keys ...string) (float64, bool) {
+	for _, k := range keys {
+		switch v := l.Data[k].(type) {
+		case float64:
+			return v, true
+		case string:
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				return f, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,113 @@
+package http
+
+import "testing"
+
+func TestParseEnhancedCode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want EnhancedCode
+		ok   bool
+	}{
+		{"5.1.1", EnhancedCode{5, 1, 1}, true},
+		{"4.7.0", EnhancedCode{4, 7, 0}, true},
+		{"2.0.0", EnhancedCode{2, 0, 0}, true},
+		{"5.100.999", EnhancedCode{5, 100, 999}, true},
+		{"3.1.1", EnhancedCode{}, false},
+		{"5.1", EnhancedCode{}, false},
+		{"5.1.1.1", EnhancedCode{}, false},
+		{"5..1", EnhancedCode{}, false},
+		{"5.1000.1", EnhancedCode{}, false},
+		{"5.-1.1", EnhancedCode{}, false},
+		{"5.a.1", EnhancedCode{}, false},
+		{"", EnhancedCode{}, false},
+	}
+	for _, tc := range tests {
+		got, ok := ParseEnhancedCode(tc.in)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("ParseEnhancedCode(%q) = %v, %v, want %v, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestParseSMTPReply(t *testing.T) {
+	tests := []struct {
+		name string
+		code int
+		msg  string
+		want SMTPReply
+	}{
+		{"code and enhanced code", 550, "550 5.1.1 <ann@example.com>: Recipient address rejected",
+			SMTPReply{550, EnhancedCode{5, 1, 1}, "<ann@example.com>: Recipient address rejected"}},
+		{"text only", 421, "Service not available",
+			SMTPReply{421, EnhancedCode{}, "Service not available"}},
+		{"code from msg", 0, "452 4.2.2 Mailbox full",
+			SMTPReply{452, EnhancedCode{4, 2, 2}, "Mailbox full"}},
+		{"code argument wins", 554, "550 5.7.1 Blocked",
+			SMTPReply{554, EnhancedCode{5, 7, 1}, "Blocked"}},
+		{"multiline", 550, "550-5.7.26 This mail is unauthenticated.\n" +
+			"550-5.7.26 Please set up SPF.\r\n" +
+			"550 5.7.26 https://support.example.com",
+			SMTPReply{550, EnhancedCode{5, 7, 26}, "This mail is unauthenticated. Please set up SPF. https://support.example.com"}},
+		{"code only", 0, "250",
+			SMTPReply{250, EnhancedCode{}, ""}},
+		{"number that is not a code", 0, "1234 items",
+			SMTPReply{0, EnhancedCode{}, "1234 items"}},
+		{"empty", 0, "", SMTPReply{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ParseSMTPReply(tc.code, tc.msg); got != tc.want {
+				t.Errorf("ParseSMTPReply = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		code int
+		msg  string
+		want Bounce
+	}{
+		{250, "250 2.0.0 Ok: queued as 4Jx2kQ0Qz3z9sT", BounceNone},
+		{0, "", BounceNone},
+		{421, "421 4.7.0 Try again later", BounceDeferral},
+		{452, "452 4.2.2 Mailbox full", BounceDeferral},
+		{451, "451 Temporary local problem", BounceDeferral},
+		{550, "550 5.1.1 User unknown", BounceHard},
+		{550, "550 5.2.1 Mailbox disabled", BounceHard},
+		{552, "552 5.2.2 Mailbox full", BounceSoft},
+		{552, "552 5.3.4 Message too big", BounceSoft},
+		{554, "554 5.7.1 Rejected", BouncePolicy},
+		{550, "550 Message rejected as spam", BouncePolicy},
+		{550, "550 5.0.0 IP listed on a blocklist", BouncePolicy},
+		{552, "552 Requested mail action aborted: exceeded storage allocation", BounceSoft},
+		{550, "550 No such user here", BounceHard},
+		{550, "550 5.0.0 No such user here", BounceHard},
+		{550, "550 5.4.4 Unable to route", BounceSoft},
+	}
+	for _, tc := range tests {
+		if got := ParseSMTPReply(tc.code, tc.msg).Classify(); got != tc.want {
+			t.Errorf("Classify(%d %q) = %q, want %q", tc.code, tc.msg, got, tc.want)
+		}
+	}
+}
+
+func TestMailLogBounce(t *testing.T) {
+	tests := []struct {
+		name string
+		log  MailLog
+		want Bounce
+	}{
+		{"delivered", MailLog{Status: MailStatusDelivered, SMTPCode: 250, Msg: "250 Ok"}, BounceNone},
+		{"deferred", MailLog{Status: MailStatusReceived, SMTPCode: 451, Msg: "451 4.3.0 Try later"}, BounceDeferral},
+		{"retries exhausted", MailLog{Status: MailStatusFailed, SMTPCode: 451, Msg: "451 4.3.0 Try later"}, BounceSoft},
+		{"failed without reply", MailLog{Status: MailStatusFailed}, BounceNone},
+		{"pending", MailLog{Status: MailStatusPending}, BounceNone},
+	}
+	for _, tc := range tests {
+		if got := tc.log.Bounce(); got != tc.want {
+			t.Errorf("%s: Bounce = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSSELineBytes is the longest line accepted from an event stream.
+const maxSSELineBytes = 1 << 20
+
+// sseEvent a single server-sent event.
+type sseEvent struct {
+	ID    string
+	Event string
+	Data  string
+}
+
+// sseReader parses a text/event-stream body as described by the WHATWG
+// HTML specification.
+type sseReader struct {
+	sc *bufio.Scanner
+
+	// lastEventID is the id of the last event received, including events
+	// that carried no data and so were not returned by next.
+	lastEventID string
+
+	// retry is the reconnection time last sent by the server, or zero.
+	retry time.Duration
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 4096), maxSSELineBytes)
+	return &sseReader{sc: sc}
+}
+
+// next returns the next complete event. Events without any data lines are
+// not returned but their id and retry fields are still applied. It returns
+// io.EOF when the stream ends, discarding any partially received event.
+func (s *sseReader) next() (*sseEvent, error) {
+	var ev sseEvent
+	var data []string
+	id, idSet := "", false
+	for s.sc.Scan() {
+		line := s.sc.Text()
+		if line == "" {
+			if idSet {
+				s.lastEventID = id
+			}
+			if data == nil {
+				ev, id, idSet = sseEvent{}, "", false
+				continue
+			}
+			ev.ID = s.lastEventID
+			ev.Data = strings.Join(data, "\n")
+			return &ev, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			// comment, typically a keep-alive
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				id, idSet = value, true
+			}
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if err := s.sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
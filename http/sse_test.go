@@ -0,0 +1,87 @@
+package http
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEReader(t *testing.T) {
+	stream := ": keep-alive\n" +
+		"\n" +
+		"retry: 3000\n" +
+		"\n" +
+		"id: 7\n" +
+		"\n" +
+		"event: mail-log\n" +
+		"data: {\"a\":1,\n" +
+		"data:\"b\":2}\n" +
+		"\n" +
+		"data: no id\n" +
+		"unknown: ignored\n" +
+		"\n" +
+		"id: 9\n" +
+		"data\n" +
+		"\n" +
+		"id\n" +
+		"data: id cleared\n" +
+		"\n" +
+		"retry: soon\n" +
+		"data: partial"
+
+	r := newSSEReader(strings.NewReader(stream))
+	var got []sseEvent
+	for {
+		ev, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, *ev)
+	}
+
+	want := []sseEvent{
+		{ID: "7", Event: "mail-log", Data: "{\"a\":1,\n\"b\":2}"},
+		{ID: "7", Data: "no id"},
+		{ID: "9", Data: ""},
+		{ID: "", Data: "id cleared"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events\n%+v\nwant\n%+v", got, want)
+	}
+	if r.retry != 3*time.Second {
+		t.Errorf("retry %s, want 3s", r.retry)
+	}
+}
+
+func TestSSEReaderDataless(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		id     string
+		retry  time.Duration
+	}{
+		{"retry only", "retry: 3000\n\n", "", 3 * time.Second},
+		{"id only", "id: 42\n\n", "42", 0},
+		{"event only", "event: mail-log\nid: 5\n\n", "5", 0},
+		{"unterminated id", "id: 42\n", "", 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newSSEReader(strings.NewReader(tc.stream))
+			if ev, err := r.next(); err != io.EOF {
+				t.Fatalf("next = %+v, %v, want io.EOF", ev, err)
+			}
+			if r.lastEventID != tc.id {
+				t.Errorf("lastEventID %q, want %q", r.lastEventID, tc.id)
+			}
+			if r.retry != tc.retry {
+				t.Errorf("retry %s, want %s", r.retry, tc.retry)
+			}
+		})
+	}
+}
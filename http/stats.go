@@ -0,0 +1,299 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Stats grouping keys.
+const (
+	StatsByStatus    = "status"
+	StatsByTemplate  = "template"
+	StatsByTransport = "transport"
+)
+
+// StatsParams parameters for GetStats.
+type StatsParams struct {
+	// Since is the start of the reporting period. Left unset defaults to
+	// seven days before Until.
+	Since time.Time
+
+	// Until is the end of the reporting period. Left unset defaults to now.
+	Until time.Time
+
+	// Bucket is the width of each time bucket. Left unset defaults to one
+	// hour for periods up to two days and one day otherwise.
+	Bucket time.Duration
+
+	// GroupBy one of StatsByStatus, StatsByTemplate or StatsByTransport.
+	// Left unset defaults to StatsByStatus.
+	GroupBy string
+
+	// ClientSide skips the stats endpoint and aggregates ListMailPage and
+	// ListMailLogs results locally.
+	ClientSide bool
+}
+
+// Stats delivery statistics for a reporting period.
+type Stats struct {
+	Since   time.Time
+	Until   time.Time
+	Bucket  time.Duration
+	GroupBy string
+
+	// Total number of mail entries created in the period.
+	Total int
+
+	// ByStatus counts of mail entries by current status.
+	ByStatus map[MailStatus]int
+
+	// Groups per GroupBy key, ordered by descending total.
+	Groups []StatsGroup
+
+	// Delivery is the time from creation to the delivered mail log.
+	Delivery LatencyPercentiles
+
+	// ClientSide is true if the stats were aggregated locally because
+	// the API does not offer a stats endpoint.
+	ClientSide bool
+}
+
+// StatsGroup counts for a single GroupBy key. Buckets holds one count per
+// time bucket starting at Stats.Since.
+type StatsGroup struct {
+	Key     string
+	Total   int
+	Buckets []int
+}
+
+// LatencyPercentiles summary of a set of durations.
+type LatencyPercentiles struct {
+	Count int
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// statsResponse wire format of the stats endpoint.
+type statsResponse struct {
+	Since         time.Time          `json:"since"`
+	Until         time.Time          `json:"until"`
+	BucketSeconds int64              `json:"bucketSeconds"`
+	GroupBy       string             `json:"groupBy"`
+	Total         int                `json:"total"`
+	ByStatus      map[MailStatus]int `json:"byStatus"`
+	Groups        []struct {
+		Key     string `json:"key"`
+		Total   int    `json:"total"`
+		Buckets []int  `json:"buckets"`
+	} `json:"groups"`
+	Delivery struct {
+		Count int   `json:"count"`
+		P50MS int64 `json:"p50Ms"`
+		P90MS int64 `json:"p90Ms"`
+		P99MS int64 `json:"p99Ms"`
+		MaxMS int64 `json:"maxMs"`
+	} `json:"delivery"`
+}
+
+// GetStats fetches delivery statistics for a project. If the API does not
+// offer a stats endpoint the statistics are aggregated client-side from
+// every page of ListMailPage and ListMailLogs, which makes one request per
+// delivered mail.
+func (c *Client) GetStats(ctx context.Context, projectID string, params StatsParams) (*Stats, error) {
+	if params.Until.IsZero() {
+		params.Until = time.Now()
+	}
+	if params.Since.IsZero() {
+		params.Since = params.Until.Add(-7 * 24 * time.Hour)
+	}
+	if !params.Since.Before(params.Until) {
+		return nil, &ValidationError{Field: "since", Msg: "must be before until"}
+	}
+	if params.Bucket <= 0 {
+		params.Bucket = time.Hour
+		if params.Until.Sub(params.Since) > 48*time.Hour {
+			params.Bucket = 24 * time.Hour
+		}
+	}
+	switch params.GroupBy {
+	case "":
+		params.GroupBy = StatsByStatus
+	case StatsByStatus, StatsByTemplate, StatsByTransport:
+	default:
+		return nil, &ValidationError{Field: "group by", Value: params.GroupBy,
+			Msg: "must be status, template or transport"}
+	}
+
+	if !params.ClientSide {
+		stats, err := c.fetchStats(ctx, projectID, params)
+		if err != errStatsUnsupported {
+			return stats, err
+		}
+	}
+	return c.aggregateStats(ctx, projectID, params)
+}
+
+var errStatsUnsupported = errors.New("stats endpoint not supported")
+
+func (c *Client) fetchStats(ctx context.Context, projectID string, params StatsParams) (*Stats, error) {
+	query := url.Values{
+		"since":  []string{params.Since.UTC().Format(time.RFC3339)},
+		"until":  []string{params.Until.UTC().Format(time.RFC3339)},
+		"bucket": []string{strconv.FormatInt(int64(params.Bucket/time.Second), 10)},
+		"by":     []string{params.GroupBy},
+	}
+	path, err := pathf("projects/%s/stats", c.project(projectID))
+	if err != nil {
+		return nil, err
+	}
+	uri := c.buildURL(path, query)
+	res, err := c.request(ctx, http.MethodGet, uri.String(), nil)
+	if terr, ok := err.(*APIError); ok {
+		switch terr.Status {
+		case http.StatusNotFound:
+			// distinguish a missing project from a missing endpoint
+			if terr.Code == ErrCodeProjectNotFound {
+				return nil, terr
+			}
+			return nil, errStatsUnsupported
+		case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+			return nil, errStatsUnsupported
+		}
+	}
+	if err != nil {
+		return nil, requestError(err, "http get request failed")
+	}
+	defer res.Body.Close()
+
+	var container struct {
+		Data *statsResponse `json:"data"`
+	}
+	if err := c.decode(res.Body, &container); err != nil {
+		return nil, errors.Wrapf(err, "json decode get stats")
+	}
+	if container.Data == nil {
+		return nil, errors.New("stats response has no data")
+	}
+
+	d := container.Data
+	stats := &Stats{
+		Since:    d.Since,
+		Until:    d.Until,
+		Bucket:   time.Duration(d.BucketSeconds) * time.Second,
+		GroupBy:  d.GroupBy,
+		Total:    d.Total,
+		ByStatus: d.ByStatus,
+		Delivery: LatencyPercentiles{
+			Count: d.Delivery.Count,
+			P50:   time.Duration(d.Delivery.P50MS) * time.Millisecond,
+			P90:   time.Duration(d.Delivery.P90MS) * time.Millisecond,
+			P99:   time.Duration(d.Delivery.P99MS) * time.Millisecond,
+			Max:   time.Duration(d.Delivery.MaxMS) * time.Millisecond,
+		},
+	}
+	for _, g := range d.Groups {
+		stats.Groups = append(stats.Groups, StatsGroup{Key: g.Key, Total: g.Total, Buckets: g.Buckets})
+	}
+	return stats, nil
+}
+
+func (c *Client) aggregateStats(ctx context.Context, projectID string, params StatsParams) (*Stats, error) {
+	n := int((params.Until.Sub(params.Since) + params.Bucket - 1) / params.Bucket)
+	stats := &Stats{
+		Since:      params.Since,
+		Until:      params.Until,
+		Bucket:     params.Bucket,
+		GroupBy:    params.GroupBy,
+		ByStatus:   make(map[MailStatus]int),
+		ClientSide: true,
+	}
+	groups := make(map[string]*StatsGroup)
+	var latencies []time.Duration
+
+	it := c.IterateMail(ctx, projectID, ListMailParams{
+		Since: params.Since,
+		Until: params.Until,
+	})
+	for it.Next() {
+		m := it.Mail()
+		stats.Total++
+		stats.ByStatus[m.Status]++
+
+		var key string
+		switch params.GroupBy {
+		case StatsByStatus:
+			key = m.Status.String()
+		case StatsByTemplate:
+			key = m.TemplateID
+		case StatsByTransport:
+			key = m.TransportID
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &StatsGroup{Key: key, Buckets: make([]int, n)}
+			groups[key] = g
+		}
+		g.Total++
+		g.Buckets[int(m.CreatedAt.Sub(params.Since)/params.Bucket)]++
+
+		if m.Status != MailStatusDelivered {
+			continue
+		}
+		logs, err := c.ListMailLogs(ctx, projectID, m.ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "aggregate stats for mail %s", m.ID)
+		}
+		for _, l := range logs {
+			if l.Status == MailStatusDelivered {
+				latencies = append(latencies, l.CreatedAt.Sub(m.CreatedAt))
+				break
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, errors.Wrap(err, "aggregate stats")
+	}
+
+	for _, g := range groups {
+		stats.Groups = append(stats.Groups, *g)
+	}
+	sort.Slice(stats.Groups, func(i, j int) bool {
+		if stats.Groups[i].Total != stats.Groups[j].Total {
+			return stats.Groups[i].Total > stats.Groups[j].Total
+		}
+		return stats.Groups[i].Key < stats.Groups[j].Key
+	})
+	stats.Delivery = percentiles(latencies)
+	return stats, nil
+}
+
+// percentiles computes nearest-rank percentiles of d. The slice is sorted
+// in place.
+func percentiles(d []time.Duration) LatencyPercentiles {
+	if len(d) == 0 {
+		return LatencyPercentiles{}
+	}
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	rank := func(p int) time.Duration {
+		i := (p*len(d)+99)/100 - 1
+		if i < 0 {
+			i = 0
+		}
+		return d[i]
+	}
+	return LatencyPercentiles{
+		Count: len(d),
+		P50:   rank(50),
+		P90:   rank(90),
+		P99:   rank(99),
+		Max:   d[len(d)-1],
+	}
+}
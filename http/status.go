@@ -0,0 +1,175 @@
+package http
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MailStatus the delivery status of a mail entry or mail log.
+//
+// Mail moves through the following state machine. Each mail log records
+// one transition.
+//
+//	scheduled ──► pending ──► published ──► received ──► delivered
+//	    │            │            │            │
+//	    │            │            └────────────┴──► failed
+//	    └────────────┴──► cancelled
+//
+// Mail in the received status may log received again while the remote MTA
+// defers delivery. Delivered, failed and cancelled are terminal; only
+// ResendMail moves delivered or failed mail back to pending.
+//
+// Values not known to this version of the client are preserved as is so
+// that new server statuses round trip through JSON unchanged.
+type MailStatus string
+
+const (
+	// MailStatusScheduled mail is waiting for its SendAt time.
+	MailStatusScheduled MailStatus = "scheduled"
+
+	// MailStatusPending mail has been accepted and is waiting to be sent.
+	MailStatusPending MailStatus = "pending"
+
+	// MailStatusPublished mail has been published to the send queue.
+	MailStatusPublished MailStatus = "published"
+
+	// MailStatusReceived mail has been received by the sending worker.
+	MailStatusReceived MailStatus = "received"
+
+	// MailStatusDelivered mail has been accepted by the remote MTA.
+	MailStatusDelivered MailStatus = "delivered"
+
+	// MailStatusFailed mail could not be delivered.
+	MailStatusFailed MailStatus = "failed"
+
+	// MailStatusCancelled mail was cancelled before it was sent.
+	MailStatusCancelled MailStatus = "cancelled"
+)
+
+// MailStatuses all statuses known to this version of the client in state
+// machine order.
+var MailStatuses = []MailStatus{
+	MailStatusScheduled,
+	MailStatusPending,
+	MailStatusPublished,
+	MailStatusReceived,
+	MailStatusDelivered,
+	MailStatusFailed,
+	MailStatusCancelled,
+}
+
+// mailTransitions maps each status to the statuses it may move to.
+var mailTransitions = map[MailStatus][]MailStatus{
+	MailStatusScheduled: {MailStatusPending, MailStatusCancelled},
+	MailStatusPending:   {MailStatusPublished, MailStatusCancelled, MailStatusFailed},
+	MailStatusPublished: {MailStatusReceived, MailStatusFailed},
+	MailStatusReceived:  {MailStatusReceived, MailStatusDelivered, MailStatusFailed},
+	MailStatusDelivered: {MailStatusPending},
+	MailStatusFailed:    {MailStatusPending},
+	MailStatusCancelled: {},
+}
+
+// String representation of a MailStatus.
+func (s MailStatus) String() string {
+	return string(s)
+}
+
+// IsKnown reports whether s is one of MailStatuses.
+func (s MailStatus) IsKnown() bool {
+	_, ok := mailTransitions[s]
+	return ok
+}
+
+// IsTerminal reports whether no further logs are expected for mail in
+// status s.
+func (s MailStatus) IsTerminal() bool {
+	return s == MailStatusDelivered || s == MailStatusFailed || s == MailStatusCancelled
+}
+
+// IsFailure reports whether s means the mail was not delivered.
+func (s MailStatus) IsFailure() bool {
+	return s == MailStatusFailed
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s MailStatus) MarshalText() ([]byte, error) {
+	return []byte(s), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Known statuses are
+// matched case-insensitively; unknown values are preserved.
+func (s *MailStatus) UnmarshalText(b []byte) error {
+	v := MailStatus(b)
+	if l := MailStatus(strings.ToLower(strings.TrimSpace(string(b)))); l.IsKnown() {
+		v = l
+	}
+	*s = v
+	return nil
+}
+
+// ParseMailStatus parses a known mail status.
+func ParseMailStatus(s string) (MailStatus, error) {
+	var v MailStatus
+	v.UnmarshalText([]byte(s))
+	if !v.IsKnown() {
+		return v, &ValidationError{Field: "status", Value: s, Msg: "unknown mail status"}
+	}
+	return v, nil
+}
+
+// CanTransition reports whether mail may move from one status to another.
+// Transitions involving a status unknown to this client are allowed.
+func CanTransition(from, to MailStatus) bool {
+	next, ok := mailTransitions[from]
+	if !ok || !to.IsKnown() {
+		return true
+	}
+	for _, v := range next {
+		if v == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError describes the first invalid step found by
+// ValidateMailLogs.
+type TransitionError struct {
+	Index int // index of the offending log
+	LogID string
+	From  MailStatus
+	To    MailStatus
+	Msg   string
+}
+
+// Error string representation of a TransitionError.
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("mail log %d (%s): %s → %s: %s", e.Index, e.LogID, e.From, e.To, e.Msg)
+}
+
+// ValidateMailLogs checks that logs, in the order given, follow the mail
+// status state machine and that their timestamps do not go backwards. The
+// first log must be scheduled or pending. It returns a *TransitionError
+// describing the first problem found.
+func ValidateMailLogs(logs []MailLog) error {
+	for i, l := range logs {
+		if i == 0 {
+			if l.Status.IsKnown() && l.Status != MailStatusScheduled && l.Status != MailStatusPending {
+				return &TransitionError{Index: i, LogID: l.ID, To: l.Status,
+					Msg: "first log must be scheduled or pending"}
+			}
+			continue
+		}
+
+		prev := logs[i-1]
+		if l.CreatedAt.Before(prev.CreatedAt) {
+			return &TransitionError{Index: i, LogID: l.ID, From: prev.Status, To: l.Status,
+				Msg: "out of order: created before the previous log"}
+		}
+		if !CanTransition(prev.Status, l.Status) {
+			return &TransitionError{Index: i, LogID: l.ID, From: prev.Status, To: l.Status,
+				Msg: "invalid transition"}
+		}
+	}
+	return nil
+}
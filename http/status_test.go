@@ -0,0 +1,113 @@
+package http
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	// every legal transition; all other pairs of known statuses are illegal
+	legal := map[[2]MailStatus]bool{
+		{MailStatusScheduled, MailStatusPending}:   true,
+		{MailStatusScheduled, MailStatusCancelled}: true,
+		{MailStatusPending, MailStatusPublished}:   true,
+		{MailStatusPending, MailStatusCancelled}:   true,
+		{MailStatusPending, MailStatusFailed}:      true,
+		{MailStatusPublished, MailStatusReceived}:  true,
+		{MailStatusPublished, MailStatusFailed}:    true,
+		{MailStatusReceived, MailStatusReceived}:   true,
+		{MailStatusReceived, MailStatusDelivered}:  true,
+		{MailStatusReceived, MailStatusFailed}:     true,
+		{MailStatusDelivered, MailStatusPending}:   true,
+		{MailStatusFailed, MailStatusPending}:      true,
+	}
+	for _, from := range MailStatuses {
+		for _, to := range MailStatuses {
+			want := legal[[2]MailStatus{from, to}]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+
+	// statuses added to the server after this client are not rejected
+	unknown := MailStatus("bounced")
+	for _, s := range MailStatuses {
+		if !CanTransition(s, unknown) || !CanTransition(unknown, s) {
+			t.Errorf("transition between %s and unknown status rejected", s)
+		}
+	}
+}
+
+func TestParseMailStatus(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    MailStatus
+		wantErr bool
+	}{
+		{"delivered", MailStatusDelivered, false},
+		{" Failed ", MailStatusFailed, false},
+		{"CANCELLED", MailStatusCancelled, false},
+		{"bounced", MailStatus("bounced"), true},
+		{"", MailStatus(""), true},
+	}
+	for _, tc := range tests {
+		got, err := ParseMailStatus(tc.in)
+		if got != tc.want || (err != nil) != tc.wantErr {
+			t.Errorf("ParseMailStatus(%q) = %q, %v, want %q, error %v", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestValidateMailLogs(t *testing.T) {
+	t0 := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	logs := func(statuses ...MailStatus) []MailLog {
+		out := make([]MailLog, len(statuses))
+		for i, s := range statuses {
+			out[i] = MailLog{ID: string(rune('a' + i)), Status: s, CreatedAt: t0.Add(time.Duration(i) * time.Second)}
+		}
+		return out
+	}
+	backwards := logs(MailStatusPending, MailStatusPublished)
+	backwards[1].CreatedAt = t0.Add(-time.Second)
+
+	tests := []struct {
+		name      string
+		logs      []MailLog
+		wantIndex int // -1 if valid
+		wantMsg   string
+	}{
+		{"empty", nil, -1, ""},
+		{"delivered", logs(MailStatusPending, MailStatusPublished, MailStatusReceived,
+			MailStatusReceived, MailStatusDelivered), -1, ""},
+		{"scheduled then cancelled", logs(MailStatusScheduled, MailStatusCancelled), -1, ""},
+		{"failed then resent", logs(MailStatusPending, MailStatusFailed, MailStatusPending,
+			MailStatusPublished), -1, ""},
+		{"unknown status", logs(MailStatusPending, MailStatus("bounced"), MailStatusFailed), -1, ""},
+		{"unknown first status", logs(MailStatus("queued"), MailStatusPending), -1, ""},
+		{"starts published", logs(MailStatusPublished), 0, "first log must be scheduled or pending"},
+		{"starts delivered", logs(MailStatusDelivered), 0, "first log must be scheduled or pending"},
+		{"skips received", logs(MailStatusPending, MailStatusPublished, MailStatusDelivered), 2, "invalid transition"},
+		{"cancelled after published", logs(MailStatusPending, MailStatusPublished, MailStatusCancelled), 2, "invalid transition"},
+		{"after cancelled", logs(MailStatusScheduled, MailStatusCancelled, MailStatusPending), 2, "invalid transition"},
+		{"out of order", backwards, 1, "out of order: created before the previous log"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateMailLogs(tc.logs)
+			if tc.wantIndex < 0 {
+				if err != nil {
+					t.Errorf("ValidateMailLogs = %v, want nil", err)
+				}
+				return
+			}
+			terr, ok := err.(*TransitionError)
+			if !ok {
+				t.Fatalf("ValidateMailLogs = %v, want *TransitionError", err)
+			}
+			if terr.Index != tc.wantIndex || terr.Msg != tc.wantMsg || terr.LogID != tc.logs[tc.wantIndex].ID {
+				t.Errorf("TransitionError = %+v, want index %d %q", terr, tc.wantIndex, tc.wantMsg)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// WithTLSConfig uses cfg as the base TLS configuration. The other TLS
+// options modify a clone of cfg.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(o *options) error {
+		if cfg == nil {
+			return errors.New("tls config must not be nil")
+		}
+		o.tlsConfig = cfg.Clone()
+		return nil
+	}
+}
+
+// WithRootCAs verifies the server certificate against pool instead of the
+// system roots.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(o *options) error {
+		if pool == nil {
+			return errors.New("root CA pool must not be nil")
+		}
+		o.tls().RootCAs = pool
+		return nil
+	}
+}
+
+// WithRootCAFile verifies the server certificate against the PEM encoded
+// CA certificates in filename instead of the system roots.
+func WithRootCAFile(filename string) Option {
+	return func(o *options) error {
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			return errors.Wrap(err, "read root CA file")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return errors.Errorf("root CA file %s contains no PEM certificates", filename)
+		}
+		o.tls().RootCAs = pool
+		return nil
+	}
+}
+
+// WithClientCertificate presents cert to servers that request a client
+// certificate (mutual TLS).
+func WithClientCertificate(cert tls.Certificate) Option {
+	return func(o *options) error {
+		if len(cert.Certificate) == 0 {
+			return errors.New("client certificate is empty")
+		}
+		o.tls().Certificates = []tls.Certificate{cert}
+		return nil
+	}
+}
+
+// WithClientCertFiles loads a PEM encoded client certificate and private
+// key for mutual TLS.
+func WithClientCertFiles(certFile, keyFile string) Option {
+	return func(o *options) error {
+		if certFile == "" || keyFile == "" {
+			return errors.New("client certificate and key files must both be set")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return errors.Wrap(err, "load client certificate")
+		}
+		o.tls().Certificates = []tls.Certificate{cert}
+		return nil
+	}
+}
+
+// WithMinTLSVersion sets the minimum TLS version, e.g. tls.VersionTLS13.
+// Left unset the crypto/tls default is used.
+func WithMinTLSVersion(v uint16) Option {
+	return func(o *options) error {
+		switch v {
+		case tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13:
+		default:
+			return errors.Errorf("unknown TLS version 0x%04x", v)
+		}
+		o.tls().MinVersion = v
+		return nil
+	}
+}
+
+// WithProxy sends requests through the proxy at proxyURL. The http, https
+// and socks5 schemes are supported. Left unset no proxy is used.
+func WithProxy(proxyURL string) Option {
+	return func(o *options) error {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return errors.Wrap(err, "proxy url parse")
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return errors.Errorf("proxy %q must use the http, https or socks5 scheme", proxyURL)
+		}
+		if u.Host == "" {
+			return errors.Errorf("proxy %q has no host", proxyURL)
+		}
+		o.proxy = http.ProxyURL(u)
+		return nil
+	}
+}
+
+// WithProxyFromEnvironment uses the proxy named by the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables.
+func WithProxyFromEnvironment() Option {
+	return func(o *options) error {
+		o.proxy = http.ProxyFromEnvironment
+		return nil
+	}
+}
+
+// WithUnixSocket connects to the Unix domain socket at path instead of
+// dialing the endpoint's host. The endpoint still supplies the scheme,
+// Host header and base path, e.g. http://localhost/v1.
+func WithUnixSocket(path string) Option {
+	return func(o *options) error {
+		if path == "" {
+			return errors.New("unix socket path must not be empty")
+		}
+		o.unixSocket = path
+		return nil
+	}
+}
+
+// tls returns the TLS configuration being built, creating it if needed.
+func (o *options) tls() *tls.Config {
+	if o.tlsConfig == nil {
+		o.tlsConfig = &tls.Config{}
+	}
+	return o.tlsConfig
+}
+
+// transportOptionsSet reports whether any option that configures the
+// transport was given.
+func (o *options) transportOptionsSet() bool {
+	return o.tlsConfig != nil || o.proxy != nil || o.unixSocket != ""
+}
+
+// buildTransport returns the transport for the client, applying the TLS,
+// proxy and socket options to rt if it is an *http.Transport.
+func (o *options) buildTransport(rt http.RoundTripper) (http.RoundTripper, error) {
+	var tr *http.Transport
+	switch t := rt.(type) {
+	case nil:
+		tr = &http.Transport{
+			MaxIdleConnsPerHost: 10,
+		}
+	case *http.Transport:
+		if !o.transportOptionsSet() {
+			return t, nil
+		}
+		tr = t.Clone()
+	default:
+		if o.transportOptionsSet() {
+			return nil, errors.New("TLS, proxy and unix socket options require an *http.Transport")
+		}
+		return rt, nil
+	}
+
+	if o.tlsConfig != nil {
+		tr.TLSClientConfig = o.tlsConfig
+	}
+	if o.proxy != nil {
+		tr.Proxy = o.proxy
+	}
+	if o.unixSocket != "" {
+		path := o.unixSocket
+		var d net.Dialer
+		tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, "unix", path)
+		}
+		tr.Proxy = nil
+	}
+	return tr, nil
+}
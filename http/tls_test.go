@@ -0,0 +1,239 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testTransportsBody = `{"data":[{"id":"t1","projectId":"p1","host":"smtp.example.com"}]}`
+
+func transportsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprint(w, testTransportsBody)
+}
+
+// writePEM writes blocks of the given type to a new file in dir.
+func writePEM(t *testing.T, dir, name, typ string, blocks ...[]byte) string {
+	t.Helper()
+	f, err := os.Create(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, b := range blocks {
+		if err := pem.Encode(f, &pem.Block{Type: typ, Bytes: b}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f.Name()
+}
+
+// newClientCA returns a CA and a client certificate and key file signed by
+// it.
+func newClientCA(t *testing.T, dir string) (pool *x509.CertPool, certFile, keyFile string) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "raven test client CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "raven test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool = x509.NewCertPool()
+	pool.AddCert(ca)
+	certFile = writePEM(t, dir, "client.pem", "CERTIFICATE", der)
+	keyFile = writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", keyDER)
+	return pool, certFile, keyFile
+}
+
+func TestCustomCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(transportsHandler))
+	defer srv.Close()
+	caFile := writePEM(t, t.TempDir(), "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+	ctx := context.Background()
+
+	c, err := NewClientWithOptions(srv.URL + "/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListTransports(ctx, "p1"); err == nil {
+		t.Error("ListTransports succeeded without trusting the test CA")
+	}
+
+	c, err = NewClient(Config{Endpoint: srv.URL + "/v1", CACertFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := c.ListTransports(ctx, "p1")
+	if err != nil {
+		t.Fatalf("ListTransports with CACertFile: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != "t1" {
+		t.Errorf("ListTransports = %+v", list)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCAs, certFile, keyFile := newClientCA(t, dir)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(transportsHandler))
+	srv.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+	caFile := writePEM(t, dir, "ca.pem", "CERTIFICATE", srv.Certificate().Raw)
+	ctx := context.Background()
+
+	c, err := NewClientWithOptions(srv.URL+"/v1", WithRootCAFile(caFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListTransports(ctx, "p1"); err == nil {
+		t.Error("ListTransports succeeded without a client certificate")
+	}
+
+	c, err = NewClientWithOptions(srv.URL+"/v1",
+		WithRootCAFile(caFile),
+		WithClientCertFiles(certFile, keyFile),
+		WithMinTLSVersion(tls.VersionTLS12),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListTransports(ctx, "p1"); err != nil {
+		t.Fatalf("ListTransports with client certificate: %v", err)
+	}
+
+	c, err = NewClient(Config{
+		Endpoint:   srv.URL + "/v1",
+		CACertFile: caFile,
+		CertFile:   certFile,
+		KeyFile:    keyFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListTransports(ctx, "p1"); err != nil {
+		t.Fatalf("ListTransports with Config client certificate: %v", err)
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// a forward proxy receives the absolute request URI
+		proxied = r.URL.String()
+		transportsHandler(w, r)
+	}))
+	defer proxy.Close()
+
+	c, err := NewClient(Config{Endpoint: "http://raven.invalid/v1", Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListTransports(context.Background(), "p1"); err != nil {
+		t.Fatalf("ListTransports through proxy: %v", err)
+	}
+	if want := "http://raven.invalid/v1/projects/p1/transports"; proxied != want {
+		t.Errorf("proxy received %q, want %q", proxied, want)
+	}
+}
+
+func TestUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raven.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets not supported: %v", err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(transportsHandler)}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	c, err := NewClientWithOptions("http://localhost/v1", WithUnixSocket(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListTransports(context.Background(), "p1"); err != nil {
+		t.Fatalf("ListTransports over unix socket: %v", err)
+	}
+}
+
+func TestTLSOptionErrors(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		c    Config
+	}{
+		{"missing CA file", Config{CACertFile: filepath.Join(dir, "missing.pem")}},
+		{"CA file without certificates", Config{CACertFile: empty}},
+		{"cert without key", Config{CertFile: empty}},
+		{"unknown TLS version", Config{MinTLSVersion: 0x1234}},
+		{"proxy scheme", Config{Proxy: "ftp://proxy.example.com"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.c.Endpoint = "https://api.example.com/v1"
+			if _, err := NewClient(tc.c); err == nil {
+				t.Error("NewClient returned no error")
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package http
 
 import (
 	"fmt"
+	"io"
+	"net/http"
 	"time"
 )
 
@@ -81,6 +83,35 @@ const (
 	// ErrCodeMailTemplateExecute response error code.
 	ErrCodeMailTemplateExecute = "mail/mail-template-execute-failure"
 
+	// ErrCodeMailEmailToInvalid response error code.
+	ErrCodeMailEmailToInvalid = "mail/email-to-invalid"
+
+	// ErrCodeMailSendAtInvalid response error code.
+	ErrCodeMailSendAtInvalid = "mail/send-at-invalid"
+
+	// ErrCodeMailNotCancellable response error code.
+	ErrCodeMailNotCancellable = "mail/mail-not-cancellable"
+
+	// ErrCodeMailNotResendable response error code.
+	ErrCodeMailNotResendable = "mail/mail-not-resendable"
+
+	// ErrCodeMailAttachmentTooLarge response error code.
+	ErrCodeMailAttachmentTooLarge = "mail/attachment-too-large"
+
+	// webhooks
+
+	// ErrCodeWebhookIDInvalid response error code.
+	ErrCodeWebhookIDInvalid = "webhooks/webhook-id-invalid"
+
+	// ErrCodeWebhookNotFound response error code.
+	ErrCodeWebhookNotFound = "webhooks/webhook-not-found"
+
+	// ErrCodeWebhookURLInvalid response error code.
+	ErrCodeWebhookURLInvalid = "webhooks/webhook-url-invalid"
+
+	// ErrCodeWebhookEventInvalid response error code.
+	ErrCodeWebhookEventInvalid = "webhooks/webhook-event-invalid"
+
 	// general
 
 	// ErrCodeBadRequest response error code.
@@ -166,14 +197,148 @@ type Mail struct {
 	ID           string     `json:"id"`
 	TemplateID   string     `json:"templateId"`
 	ProjectID    string     `json:"projectId"`
-	Status       string     `json:"status"`
+	TransportID  string     `json:"transportId,omitempty"`
+	Status       MailStatus `json:"status"`
 	EmailTo      string     `json:"emailTo"`
 	EmailFrom    string     `json:"emailFrom"`
 	EmailReplyTo string     `json:"emailReplyTo"`
 	Subject      string     `json:"subject"`
 	CreatedAt    time.Time  `json:"createdAt"`
+	SendAt       *time.Time `json:"sendAt,omitempty"`
 	SentAt       *time.Time `json:"sentAt"`
 	ModifiedAt   time.Time  `json:"modifiedAt"`
+
+	To      []Address         `json:"to,omitempty"`
+	Cc      []Address         `json:"cc,omitempty"`
+	Bcc     []Address         `json:"bcc,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Recipients returns the To recipients, falling back to EmailTo for mail
+// created with a single recipient.
+func (m *Mail) Recipients() []Address {
+	if len(m.To) > 0 {
+		return m.To
+	}
+	if m.EmailTo == "" {
+		return nil
+	}
+	return []Address{{Email: m.EmailTo}}
+}
+
+// ListMailParams parameters to list a page of mail resources. All fields
+// are optional.
+type ListMailParams struct {
+	Since  time.Time
+	Until  time.Time
+	Status MailStatus
+
+	// Limit is the maximum number of results per page. Left unset the
+	// server default is used.
+	Limit int
+
+	// Cursor is the NextCursor of the previous page.
+	Cursor string
+}
+
+// MailPage a page of mail resources. NextCursor is empty on the last page.
+type MailPage struct {
+	Data       []Mail `json:"data"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// SendMailParams parameters to send a new mail using a template.
+type SendMailParams struct {
+	ProjectID  string
+	TemplateID string
+	EmailTo    string
+	Subject    string
+
+	// To, Cc and Bcc recipients. EmailTo is added to To if set.
+	To  []Address
+	Cc  []Address
+	Bcc []Address
+
+	// Headers (optional) custom headers such as List-Unsubscribe,
+	// In-Reply-To or X-Campaign. See ValidateHeader.
+	Headers map[string]string
+
+	// Params name value pairs passed to the template.
+	Params TemplateActions
+
+	// SendAt (optional) schedules the mail for delivery at a future time.
+	// Left unset the mail is sent immediately.
+	SendAt time.Time
+
+	// Attachments are added to the message as regular attachments.
+	Attachments []Attachment
+
+	// Inline are attachments referenced from the HTML body by their
+	// ContentID, e.g. <img src="cid:logo">.
+	Inline []Attachment
+
+	// IdempotencyKey (optional) identifies this send so that repeating it,
+	// e.g. when re-running a script, does not send the mail twice. Left
+	// unset a random key is used, which only protects automatic retries.
+	IdempotencyKey string
+}
+
+// Attachment file content added to a mail. Either Content or Reader must be
+// set; if both are set Content is used.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+	Reader      io.Reader
+
+	// ContentID is required for inline attachments and ignored otherwise.
+	ContentID string
+}
+
+type sendMailRequest struct {
+	TemplateID  string                  `json:"templateId"`
+	EmailTo     string                  `json:"emailTo,omitempty"`
+	To          []Address               `json:"to,omitempty"`
+	Cc          []Address               `json:"cc,omitempty"`
+	Bcc         []Address               `json:"bcc,omitempty"`
+	Headers     map[string]string       `json:"headers,omitempty"`
+	Subject     string                  `json:"subject,omitempty"`
+	Params      TemplateActions         `json:"params,omitempty"`
+	SendAt      *time.Time              `json:"sendAt,omitempty"`
+	Attachments []attachmentRequestBody `json:"attachments,omitempty"`
+}
+
+type attachmentRequestBody struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"` // base64 std encoding
+	ContentID   string `json:"contentId,omitempty"`
+	Inline      bool   `json:"inline,omitempty"`
+}
+
+// MailContent the rendered content of a mail entry exactly as sent.
+type MailContent struct {
+	MailID  string `json:"mailId"`
+	Subject string `json:"subject"`
+	Txt     string `json:"txt"`
+	HTML    string `json:"html"`
+
+	// Raw is the full RFC 5322 message source including headers.
+	Raw string `json:"raw"`
+}
+
+// ResendOptions parameters to resend a mail entry.
+type ResendOptions struct {
+	// TransportID (optional) sends using this transport instead of the
+	// project's active transport.
+	TransportID string
+
+	// IdempotencyKey (optional) see SendMailParams.IdempotencyKey.
+	IdempotencyKey string
+}
+
+type resendMailRequest struct {
+	TransportID string `json:"transportId,omitempty"`
 }
 
 // MailLog type.
@@ -181,22 +346,74 @@ type MailLog struct {
 	ID        string                 `json:"id"`
 	MailID    string                 `json:"mailId"`
 	ProjectID string                 `json:"projectId"`
-	Status    string                 `json:"status"`
+	Status    MailStatus             `json:"status"`
 	SMTPCode  int                    `json:"smtpCode"`
 	Msg       string                 `json:"message"`
 	Data      map[string]interface{} `json:"data"`
 	CreatedAt time.Time              `json:"createdAt"`
 }
 
+// Webhook resource. Secret is only populated in the response to
+// CreateWebhook and RotateWebhookSecret.
+type Webhook struct {
+	ID         string    `json:"id"`
+	ProjectID  string    `json:"projectId"`
+	URL        string    `json:"url"`
+	Events     []string  `json:"events"`
+	Secret     string    `json:"secret,omitempty"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+// CreateWebhookParams parameters to create a new webhook endpoint.
+type CreateWebhookParams struct {
+	ProjectID string
+	URL       string
+	Events    []string
+}
+
+type createWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// WebhookTestResult outcome of sending a test event to a webhook endpoint.
+type WebhookTestResult struct {
+	EventID    string `json:"eventId"`
+	EventType  string `json:"eventType"`
+	StatusCode int    `json:"statusCode"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error"`
+	DurationMS int64  `json:"durationMs"`
+}
+
 // APIError standard response format for Raven Mailer errors.
 type APIError struct {
 	Status  int    `json:"status"`
 	Code    string `json:"code"`
 	Message string `json:"message"`
+
+	// RequestID identifies the request in the server logs. Quote it in
+	// support requests.
+	RequestID string `json:"requestId,omitempty"`
+
+	// IdempotencyKey sent with the failed request, if it was mutating.
+	// Retrying with the same key is safe.
+	IdempotencyKey string `json:"-"`
+
+	header http.Header // response headers
 }
 
 // Error string representation of an APIError.
 func (e *APIError) Error() string {
-	return fmt.Sprintf("Status: %d Code: %s Message: %s",
+	s := fmt.Sprintf("Status: %d Code: %s Message: %s",
 		e.Status, e.Code, e.Message)
+	if e.RequestID != "" {
+		s += " Request-ID: " + e.RequestID
+	}
+	if e.IdempotencyKey != "" {
+		s += " Idempotency-Key: " + e.IdempotencyKey
+	}
+	return s
 }
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestBuildURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		format   string
+		args     []string
+		want     string
+	}{
+		{"base path", "https://api.example.com/v1",
+			"projects/%s/mail", []string{"p1"}, "https://api.example.com/v1/projects/p1/mail"},
+		{"base path with trailing slash", "https://api.example.com/v1/",
+			"projects/%s/mail", []string{"p1"}, "https://api.example.com/v1/projects/p1/mail"},
+		{"no base path", "https://api.example.com",
+			"projects/%s/mail", []string{"p1"}, "https://api.example.com/projects/p1/mail"},
+		{"nested base path", "https://example.com/raven/api/v1",
+			"projects/%s", []string{"p1"}, "https://example.com/raven/api/v1/projects/p1"},
+		{"IPv6 host", "http://[::1]:8080/v1",
+			"projects/%s/mail", []string{"p1"}, "http://[::1]:8080/v1/projects/p1/mail"},
+		{"IPv6 host without port", "https://[2001:db8::1]/api/v1",
+			"projects/%s", []string{"p1"}, "https://[2001:db8::1]/api/v1/projects/p1"},
+		{"default https port kept", "https://api.example.com:443/v1",
+			"projects/%s", []string{"p1"}, "https://api.example.com:443/v1/projects/p1"},
+		{"default http port kept", "http://api.example.com:80/v1",
+			"projects/%s", []string{"p1"}, "http://api.example.com:80/v1/projects/p1"},
+		{"escaped base path", "https://example.com/a%2Fb/v1",
+			"projects/%s", []string{"p1"}, "https://example.com/a%2Fb/v1/projects/p1"},
+		{"slash in id", "https://api.example.com/v1",
+			"projects/%s/mail/%s", []string{"p1", "a/b"}, "https://api.example.com/v1/projects/p1/mail/a%2Fb"},
+		{"question mark in id", "https://api.example.com/v1",
+			"projects/%s/mail/%s", []string{"p1", "a?b=c"}, "https://api.example.com/v1/projects/p1/mail/a%3Fb=c"},
+		{"hash in id", "https://api.example.com/v1",
+			"projects/%s/mail/%s", []string{"p1", "a#b"}, "https://api.example.com/v1/projects/p1/mail/a%23b"},
+		{"dot dot id", "https://api.example.com/v1",
+			"projects/%s/mail/%s", []string{"p1", ".."}, "https://api.example.com/v1/projects/p1/mail/%2E%2E"},
+		{"dot id", "https://api.example.com/v1",
+			"projects/%s/mail/%s", []string{"p1", "."}, "https://api.example.com/v1/projects/p1/mail/%2E"},
+		{"dot dot slash id", "https://api.example.com/v1",
+			"projects/%s/mail/%s", []string{"p1", "../x"}, "https://api.example.com/v1/projects/p1/mail/..%2Fx"},
+		{"escaped slash id", "https://api.example.com/v1",
+			"projects/%s/mail/%s", []string{"p1", "a%2Fb"}, "https://api.example.com/v1/projects/p1/mail/a%252Fb"},
+		{"space in id", "https://api.example.com/v1",
+			"projects/%s", []string{"my project"}, "https://api.example.com/v1/projects/my%20project"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClientWithOptions(tc.endpoint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path, err := pathf(tc.format, tc.args...)
+			if err != nil {
+				t.Fatalf("pathf: %v", err)
+			}
+			if got := c.buildURL(path, nil).String(); got != tc.want {
+				t.Errorf("buildURL = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPathfEmptySegment(t *testing.T) {
+	tests := [][]string{
+		{""},
+		{"p1", ""},
+		{"", "m1"},
+	}
+	for _, args := range tests {
+		format := "projects/%s"
+		if len(args) == 2 {
+			format = "projects/%s/mail/%s"
+		}
+		if _, err := pathf(format, args...); err == nil {
+			t.Errorf("pathf(%q, %q) returned no error", format, args)
+		} else if _, ok := err.(*ValidationError); !ok {
+			t.Errorf("pathf(%q, %q) error = %T, want *ValidationError", format, args, err)
+		}
+	}
+}
+
+func TestEmptyIDNotRequested(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+	ctx := context.Background()
+
+	if _, err := c.GetMail(ctx, "p1", ""); err == nil {
+		t.Error("GetMail with an empty mail id returned no error")
+	}
+	if err := c.DeleteTemplate(ctx, "p1", ""); err == nil {
+		t.Error("DeleteTemplate with an empty template id returned no error")
+	}
+	// no project id and no default project
+	if _, err := c.ListMail(ctx, ""); err == nil {
+		t.Error("ListMail with an empty project id returned no error")
+	}
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultWaitMinInterval = 1 * time.Second
+	defaultWaitMaxInterval = 30 * time.Second
+)
+
+// WaitOptions parameters for WaitForMail.
+type WaitOptions struct {
+	// For is the set of statuses that satisfy the wait. Left unset
+	// defaults to any terminal status (see MailStatus.IsTerminal).
+	For []MailStatus
+
+	// MinInterval is the initial delay between polls and the delay used
+	// after new logs are seen. Left unset defaults to 1s.
+	MinInterval time.Duration
+
+	// MaxInterval caps the delay between polls as it doubles while
+	// nothing changes. Left unset defaults to 30s.
+	MaxInterval time.Duration
+
+	// Logs (optional) receives each new MailLog in the order returned by
+	// the API. WaitForMail closes the channel when it returns.
+	Logs chan<- MailLog
+}
+
+// WaitError is returned by WaitForMail when the mail reaches a terminal
+// status that is not one of WaitOptions.For.
+type WaitError struct {
+	Mail *Mail
+	For  []MailStatus
+}
+
+// Error string representation of a WaitError.
+func (e *WaitError) Error() string {
+	return "mail " + e.Mail.ID + " reached terminal status " + e.Mail.Status.String()
+}
+
+// WaitForMail polls GetMail and ListMailLogs with exponential backoff until
+// the mail reaches one of opts.For, reaches another terminal status, or ctx
+// is done. It returns the last mail fetched. A *WaitError is returned if
+// the mail reaches an unwanted terminal status and ctx.Err() if ctx
+// expires first.
+func (c *Client) WaitForMail(ctx context.Context, projectID, mailID string, opts WaitOptions) (*Mail, error) {
+	if opts.Logs != nil {
+		defer close(opts.Logs)
+	}
+	if opts.MinInterval <= 0 {
+		opts.MinInterval = defaultWaitMinInterval
+	}
+	if opts.MaxInterval < opts.MinInterval {
+		opts.MaxInterval = defaultWaitMaxInterval
+		if opts.MaxInterval < opts.MinInterval {
+			opts.MaxInterval = opts.MinInterval
+		}
+	}
+
+	seen := make(map[string]struct{})
+	interval := opts.MinInterval
+	var last *Mail
+	for {
+		mail, err := c.GetMail(ctx, projectID, mailID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return last, ctx.Err()
+			}
+			return last, errors.Wrapf(err, "wait for mail %s", mailID)
+		}
+		if last != nil && last.Status != mail.Status {
+			interval = opts.MinInterval
+		}
+		last = mail
+
+		if opts.Logs != nil {
+			logs, err := c.ListMailLogs(ctx, projectID, mailID)
+			if err != nil {
+				if ctx.Err() != nil {
+					return last, ctx.Err()
+				}
+				return last, errors.Wrapf(err, "wait for mail %s", mailID)
+			}
+			fresh := false
+			for _, l := range logs {
+				if _, ok := seen[l.ID]; ok {
+					continue
+				}
+				seen[l.ID] = struct{}{}
+				fresh = true
+				select {
+				case opts.Logs <- l:
+				case <-ctx.Done():
+					return last, ctx.Err()
+				}
+			}
+			if fresh {
+				interval = opts.MinInterval
+			}
+		}
+
+		if len(opts.For) == 0 && mail.Status.IsTerminal() {
+			return mail, nil
+		}
+		if containsStatus(opts.For, mail.Status) {
+			return mail, nil
+		}
+		if mail.Status.IsTerminal() {
+			return mail, &WaitError{Mail: mail, For: opts.For}
+		}
+
+		t := time.NewTimer(interval)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return last, ctx.Err()
+		}
+		interval *= 2
+		if interval > opts.MaxInterval {
+			interval = opts.MaxInterval
+		}
+	}
+}
+
+func containsStatus(list []MailStatus, s MailStatus) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
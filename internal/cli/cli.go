@@ -1,9 +1,12 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -78,6 +81,7 @@ func NewCmdCreate() *cobra.Command {
 	}
 	cmd.AddCommand(NewCmdCreateGroup())
 	cmd.AddCommand(NewCmdCreateTemplate())
+	cmd.AddCommand(NewCmdCreateWebhook())
 	return cmd
 }
 
@@ -93,6 +97,7 @@ func NewCmdList() *cobra.Command {
 	cmd.AddCommand(NewCmdListTemplates())
 	cmd.AddCommand(NewCmdListMail())
 	cmd.AddCommand(NewCmdListMailLogs())
+	cmd.AddCommand(NewCmdListWebhooks())
 	return cmd
 }
 
@@ -126,6 +131,47 @@ func NewCmdDelete() *cobra.Command {
 	}
 	cmd.AddCommand(NewCmdDeleteGroup())
 	cmd.AddCommand(NewCmdDeleteTemplate())
+	cmd.AddCommand(NewCmdDeleteWebhook())
+	return cmd
+}
+
+// NewCmdCancel cancel sub command.
+func NewCmdCancel() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cancel",
+		Short: "Cancel a resource",
+	}
+	cmd.AddCommand(NewCmdCancelMail())
+	return cmd
+}
+
+// NewCmdExport export sub command.
+func NewCmdExport() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export resources to files",
+	}
+	cmd.AddCommand(NewCmdExportMail())
+	return cmd
+}
+
+// NewCmdResend resend sub command.
+func NewCmdResend() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resend",
+		Short: "Resend a resource",
+	}
+	cmd.AddCommand(NewCmdResendMail())
+	return cmd
+}
+
+// NewCmdWait wait sub command.
+func NewCmdWait() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "wait",
+		Short: "Wait for a resource to reach a status",
+	}
+	cmd.AddCommand(NewCmdWaitMail())
 	return cmd
 }
 
@@ -224,7 +270,7 @@ func renderTable(w io.Writer, results interface{}, format string, headers []inte
 				v.ID,
 				v.TemplateID,
 				renderMailStatus(v.Status),
-				v.EmailTo,
+				renderMailTo(v),
 				v.CreatedAt,
 				renderMailSentAt(v.SentAt),
 			}
@@ -236,12 +282,24 @@ func renderTable(w io.Writer, results interface{}, format string, headers []inte
 			row := []interface{}{
 				renderIndent(i, size) + " " + v.ID,
 				renderMailStatus(v.Status),
-				v.SMTPCode,
+				renderSMTPCode(v),
+				renderBounce(v.Bounce()),
 				v.Msg,
 				renderRelativeTime(rel, v.CreatedAt),
 			}
 			fmt.Fprintf(tw, format, row...)
 		}
+	case []http.Webhook:
+		for _, v := range list {
+			row := []interface{}{
+				v.ID,
+				v.URL,
+				strings.Join(v.Events, ","),
+				renderTransportActive(v.Active),
+				v.CreatedAt,
+			}
+			fmt.Fprintf(tw, format, row...)
+		}
 	default:
 		return fmt.Errorf("unknown results type")
 	}
@@ -270,6 +328,19 @@ func renderRelativeTime(u, t time.Time) string {
 	return fmt.Sprintf("%s", t.Sub(u))
 }
 
+// renderMailTo shows the first recipient and a count of any others.
+func renderMailTo(m http.Mail) string {
+	to := m.Recipients()
+	if len(to) == 0 {
+		return ""
+	}
+	n := len(to) - 1 + len(m.Cc) + len(m.Bcc)
+	if n == 0 {
+		return to[0].Email
+	}
+	return fmt.Sprintf("%s (+%d)", to[0].Email, n)
+}
+
 func renderMailSentAt(t *time.Time) string {
 	if t == nil {
 		return "Not sent"
@@ -277,31 +348,118 @@ func renderMailSentAt(t *time.Time) string {
 	return t.Format(time.RFC1123)
 }
 
-func renderMailStatus(s string) string {
+func renderMailStatus(s http.MailStatus) string {
+	title := strings.Title(s.String())
 	switch s {
-	case "pending":
-		return "↓ " + strings.Title(s) // 💾, ⛁, ↓, ▼
-	case "published":
-		return "→ " + strings.Title(s) // 🖧, →
-	case "received":
-		return "← " + strings.Title(s) // or 🖃
-	case "delivered":
-		return "✔ " + strings.Title(s)
+	case http.MailStatusScheduled:
+		return "⏲ " + title
+	case http.MailStatusPending:
+		return "↓ " + title // 💾, ⛁, ↓, ▼
+	case http.MailStatusPublished:
+		return "→ " + title // 🖧, →
+	case http.MailStatusReceived:
+		return "← " + title // or 🖃
+	case http.MailStatusDelivered:
+		return "✔ " + title
+	case http.MailStatusFailed:
+		return "✘ " + title
+	case http.MailStatusCancelled:
+		return "⊘ " + title
 	}
-	return s
+	return s.String()
+}
+
+func renderSMTPCode(l http.MailLog) string {
+	r := l.SMTPReply()
+	if r.Code == 0 {
+		return "-"
+	}
+	if r.Enhanced.IsZero() {
+		return strconv.Itoa(r.Code)
+	}
+	return strconv.Itoa(r.Code) + " " + r.Enhanced.String()
+}
+
+func renderBounce(b http.Bounce) string {
+	if b == http.BounceNone {
+		return "-"
+	}
+	return string(b)
 }
 
 // NewCmdVersion returns an instance of the version sub command.
 func NewCmdVersion(version, gitCommit, endpoint string) *cobra.Command {
-	return &cobra.Command{
+	var server bool
+	cmd := &cobra.Command{
 		Use:   "version",
 		Short: "Raven CLI Tool version",
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			// prevent root level PersistentPreRun
-		},
-		Run: func(cmd *cobra.Command, args []string) {
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
 			fmt.Printf("Raven CLI tool %s (build %s) built with endpoint %s\n",
 				version, gitCommit, endpoint)
+			fmt.Printf("Active endpoint: %s\n", app.HTTPClient.ActiveEndpoint())
+			if eps := app.HTTPClient.Endpoints(); len(eps) > 1 {
+				for i, e := range eps {
+					health := "healthy"
+					if !e.Healthy {
+						health = "unhealthy until " + e.UnhealthyUntil.Format(time.RFC3339)
+					}
+					fmt.Printf("  %d. %s (%s)\n", i+1, e.URL, health)
+				}
+			}
+			if !server {
+				return nil
+			}
+
+			info, err := app.HTTPClient.CheckCompatibility(ctx)
+			var cerr *http.CompatibilityError
+			if err != nil && !errors.As(err, &cerr) {
+				return err
+			}
+			renderServerInfo(os.Stdout, app.HTTPClient.APIVersion(), info)
+			if cerr != nil {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", cerr)
+			}
+			if info.NewerAPIVersion != "" {
+				fmt.Fprintf(os.Stderr, "warning: server also serves API %s, upgrade the client before %s is retired\n",
+					info.NewerAPIVersion, app.HTTPClient.APIVersion())
+			}
+			return nil
 		},
 	}
+	cmd.Flags().BoolVar(&server, "server", false,
+		"also show the server version, API versions and features and check compatibility")
+	return cmd
+}
+
+func renderServerInfo(w io.Writer, clientAPI string, info *http.ServerInfo) {
+	version := info.Version
+	if version == "" {
+		version = "unknown"
+	}
+	fmt.Fprintf(w, "Server version: %s\n", version)
+	if info.Legacy {
+		fmt.Fprintf(w, "API versions: %s (assumed, server has no info endpoint), client uses %s\n",
+			strings.Join(info.APIVersions, ", "), clientAPI)
+		return
+	}
+	fmt.Fprintf(w, "API versions: %s, client uses %s\n", strings.Join(info.APIVersions, ", "), clientAPI)
+	if len(info.Features) == 0 {
+		return
+	}
+	names := make([]string, 0, len(info.Features))
+	for name := range info.Features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "Features:")
+	for _, name := range names {
+		state := "off"
+		if info.Features[name] {
+			state = "on"
+		}
+		fmt.Fprintf(w, "  %s: %s\n", name, state)
+	}
 }
@@ -0,0 +1,371 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/andyfusniak/raven-client-go/http"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdExportMail export mail sub command.
+func NewCmdExportMail() *cobra.Command {
+	var output string
+	var format string
+	var since string
+	var until string
+	var status string
+	var includeLogs bool
+	cmd := &cobra.Command{
+		Use:     "mail [MAIL_ID]",
+		Short:   "Export a mail entry as .eml, or all mail as CSV or JSONL",
+		Aliases: []string{"mails"},
+		Example: `  raven export mail 6fd3a0c2 -o message.eml
+  raven export mail --format csv --since 30d --include-logs -o october.csv`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			if len(args) == 1 {
+				if format != "" && format != "eml" {
+					return errors.New("--format is only supported when exporting all mail")
+				}
+				if includeLogs {
+					return errors.New("--include-logs is only supported when exporting all mail")
+				}
+				var content *http.MailContent
+				if content, err = getMailContent(ctx, app, args[0]); err != nil {
+					return err
+				}
+
+				var w io.Writer
+				var closeOutput func() error
+				if w, closeOutput, err = openOutput(output); err != nil {
+					return err
+				}
+				defer func() {
+					if cerr := closeOutput(); err == nil {
+						err = cerr
+					}
+				}()
+				if _, err := io.WriteString(w, content.Raw); err != nil {
+					return fmt.Errorf("export mail: %w", err)
+				}
+				return nil
+			}
+
+			params := http.ListMailParams{}
+			if status != "" {
+				if params.Status, err = http.ParseMailStatus(status); err != nil {
+					return fmt.Errorf("--status: %w", err)
+				}
+			}
+			if since != "" {
+				d, err := parseDays(since)
+				if err != nil {
+					return fmt.Errorf("--since: %w", err)
+				}
+				params.Since = time.Now().Add(-d)
+			}
+			if until != "" {
+				if params.Until, err = time.Parse(time.RFC3339, until); err != nil {
+					return fmt.Errorf("--until %q must be an RFC 3339 time", until)
+				}
+			}
+			switch format {
+			case "", "csv", "jsonl":
+			default:
+				return fmt.Errorf("--format %q must be csv or jsonl", format)
+			}
+
+			w, closeOutput, err := openOutput(output)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if cerr := closeOutput(); err == nil {
+					err = cerr
+				}
+			}()
+
+			var ex mailExporter
+			if format == "jsonl" {
+				ex = &jsonlMailExporter{enc: json.NewEncoder(w)}
+			} else {
+				ex = &csvMailExporter{w: csv.NewWriter(w), includeLogs: includeLogs}
+			}
+
+			n, err := exportMail(ctx, app, params, includeLogs, ex)
+			if err != nil {
+				return err
+			}
+			if err := closeOutput(); err != nil {
+				return err
+			}
+			if output != "" && output != "-" {
+				fmt.Fprintf(os.Stderr, "exported %d mail to %s\n", n, output)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write to FILE instead of stdout")
+	cmd.Flags().StringVar(&format, "format", "", "csv or jsonl when exporting all mail (default csv)")
+	cmd.Flags().StringVar(&since, "since", "", "only export mail created within this duration, e.g. 30d")
+	cmd.Flags().StringVar(&until, "until", "", "only export mail created before this RFC 3339 time")
+	cmd.Flags().StringVar(&status, "status", "", "only export mail with this status")
+	cmd.Flags().BoolVar(&includeLogs, "include-logs", false, "include mail logs when exporting all mail (one CSV row per log)")
+	return cmd
+}
+
+// openOutput returns a buffered writer for filename, or stdout if filename
+// is empty or "-". The returned func flushes and closes the output; calls
+// after the first do nothing, so it can be deferred and also called to
+// check the error before reporting success.
+func openOutput(filename string) (io.Writer, func() error, error) {
+	if filename == "" || filename == "-" {
+		bw := bufio.NewWriter(os.Stdout)
+		return bw, bw.Flush, nil
+	}
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, nil, err
+	}
+	bw := bufio.NewWriter(f)
+	closed := false
+	return bw, func() error {
+		if closed {
+			return nil
+		}
+		closed = true
+		if err := bw.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}, nil
+}
+
+// mailExporter writes one mail, with its logs if requested, at a time.
+type mailExporter interface {
+	write(m http.Mail, logs []http.MailLog) error
+	flush() error
+}
+
+// exportMail streams every mail matching params through ex one page at a
+// time, fetching the logs of each mail if includeLogs is set.
+func exportMail(ctx context.Context, app *App, params http.ListMailParams, includeLogs bool, ex mailExporter) (int, error) {
+	n := 0
+	it := app.HTTPClient.IterateMail(ctx, app.projectID, params)
+	for it.Next() {
+		m := it.Mail()
+		var logs []http.MailLog
+		if includeLogs {
+			var err error
+			if logs, err = app.HTTPClient.ListMailLogs(ctx, app.projectID, m.ID); err != nil {
+				return n, fmt.Errorf("export mail logs for %s: %w", m.ID, err)
+			}
+		}
+		if err := ex.write(m, logs); err != nil {
+			return n, fmt.Errorf("export mail %s: %w", m.ID, err)
+		}
+		n++
+	}
+	if err := it.Err(); err != nil {
+		return n, err
+	}
+	return n, ex.flush()
+}
+
+type jsonlMailExporter struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlMailExporter) write(m http.Mail, logs []http.MailLog) error {
+	return e.enc.Encode(struct {
+		http.Mail
+		Logs []http.MailLog `json:"logs,omitempty"`
+	}{m, logs})
+}
+
+func (e *jsonlMailExporter) flush() error {
+	return nil
+}
+
+var csvMailColumns = []string{
+	"mail_id", "project_id", "template_id", "transport_id", "status",
+	"to", "cc", "bcc", "from", "reply_to", "subject",
+	"created_at", "send_at", "sent_at", "modified_at",
+}
+
+var csvLogColumns = []string{
+	"log_id", "log_status", "log_smtp_code", "log_message", "log_created_at",
+}
+
+// csvHeaderSample is the number of mail buffered to discover the data.*
+// columns before the CSV header is written.
+const csvHeaderSample = 100
+
+// csvMailExporter writes one row per mail, or one row per mail log when
+// includeLogs is set. MailLog.Data is flattened into data.* columns. The
+// header must be written before the first row, so the data columns are
+// taken from the logs of the first csvHeaderSample mail; keys first seen
+// after that are written as JSON to the data_extra column.
+type csvMailExporter struct {
+	w           *csv.Writer
+	includeLogs bool
+
+	header      bool
+	dataColumns []string
+	sample      []csvSampleMail
+}
+
+type csvSampleMail struct {
+	mail http.Mail
+	logs []http.MailLog
+}
+
+func (e *csvMailExporter) write(m http.Mail, logs []http.MailLog) error {
+	if e.header {
+		return e.writeRows(m, logs)
+	}
+	if !e.includeLogs {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+		return e.writeRows(m, logs)
+	}
+
+	e.sample = append(e.sample, csvSampleMail{mail: m, logs: logs})
+	if len(e.sample) < csvHeaderSample {
+		return nil
+	}
+	return e.flushSample()
+}
+
+// flushSample chooses the data columns from the buffered mail, writes the
+// header and then the buffered rows.
+func (e *csvMailExporter) flushSample() error {
+	seen := make(map[string]string)
+	for _, s := range e.sample {
+		for _, l := range s.logs {
+			flattenData("data", l.Data, seen)
+		}
+	}
+	for k := range seen {
+		e.dataColumns = append(e.dataColumns, k)
+	}
+	sort.Strings(e.dataColumns)
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	for _, s := range e.sample {
+		if err := e.writeRows(s.mail, s.logs); err != nil {
+			return err
+		}
+	}
+	e.sample = nil
+	return nil
+}
+
+func (e *csvMailExporter) writeRows(m http.Mail, logs []http.MailLog) error {
+	row := mailRow(m)
+	if !e.includeLogs {
+		return e.w.Write(row)
+	}
+	if len(logs) == 0 {
+		return e.w.Write(append(row, make([]string, len(csvLogColumns)+len(e.dataColumns)+1)...))
+	}
+	for _, l := range logs {
+		r := append(append([]string{}, row...),
+			l.ID, l.Status.String(), strconv.Itoa(l.SMTPCode), l.Msg, formatTime(&l.CreatedAt))
+
+		flat := make(map[string]string)
+		flattenData("data", l.Data, flat)
+		for _, c := range e.dataColumns {
+			r = append(r, flat[c])
+			delete(flat, c)
+		}
+		extra := ""
+		if len(flat) > 0 {
+			b, err := json.Marshal(flat)
+			if err != nil {
+				return err
+			}
+			extra = string(b)
+		}
+		if err := e.w.Write(append(r, extra)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *csvMailExporter) writeHeader() error {
+	h := append([]string{}, csvMailColumns...)
+	if e.includeLogs {
+		h = append(h, csvLogColumns...)
+		h = append(h, e.dataColumns...)
+		h = append(h, "data_extra")
+	}
+	e.header = true
+	return e.w.Write(h)
+}
+
+func (e *csvMailExporter) flush() error {
+	if !e.header {
+		if err := e.flushSample(); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func mailRow(m http.Mail) []string {
+	return []string{
+		m.ID, m.ProjectID, m.TemplateID, m.TransportID, m.Status.String(),
+		renderAddresses(m.Recipients()), renderAddresses(m.Cc), renderAddresses(m.Bcc),
+		m.EmailFrom, m.EmailReplyTo, m.Subject,
+		formatTime(&m.CreatedAt), formatTime(m.SendAt), formatTime(m.SentAt), formatTime(&m.ModifiedAt),
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// flattenData writes each leaf of v into out keyed by its dotted path.
+// Nested objects are descended into; arrays and other values are JSON
+// encoded, except strings which are written as is.
+func flattenData(prefix string, v interface{}, out map[string]string) {
+	switch t := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		for k, vv := range t {
+			flattenData(prefix+"."+k, vv, out)
+		}
+	case string:
+		out[prefix] = t
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			out[prefix] = fmt.Sprint(t)
+			return
+		}
+		out[prefix] = string(b)
+	}
+}
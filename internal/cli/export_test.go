@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/andyfusniak/raven-client-go/http"
+)
+
+func testExportMail() http.Mail {
+	created := time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC)
+	sent := created.Add(time.Minute)
+	return http.Mail{
+		ID:           "m1",
+		ProjectID:    "p1",
+		TemplateID:   "welcome",
+		TransportID:  "t1",
+		Status:       http.MailStatusDelivered,
+		EmailFrom:    "shop@example.com",
+		EmailReplyTo: "help@example.com",
+		Subject:      "Welcome",
+		CreatedAt:    created,
+		SentAt:       &sent,
+		ModifiedAt:   sent,
+		To:           []http.Address{{Name: "Ann", Email: "ann@example.com"}, {Email: "bob@example.com"}},
+		Cc:           []http.Address{{Email: "cat@example.com"}},
+		Bcc:          []http.Address{{Email: "audit@example.com"}},
+	}
+}
+
+func exportCSV(t *testing.T, includeLogs bool, mail []http.Mail, logs [][]http.MailLog) [][]string {
+	t.Helper()
+	var buf bytes.Buffer
+	ex := &csvMailExporter{w: csv.NewWriter(&buf), includeLogs: includeLogs}
+	for i, m := range mail {
+		var l []http.MailLog
+		if logs != nil {
+			l = logs[i]
+		}
+		if err := ex.write(m, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ex.flush(); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestCSVExportColumns(t *testing.T) {
+	records := exportCSV(t, false, []http.Mail{testExportMail()}, nil)
+
+	want := [][]string{
+		{"mail_id", "project_id", "template_id", "transport_id", "status",
+			"to", "cc", "bcc", "from", "reply_to", "subject",
+			"created_at", "send_at", "sent_at", "modified_at"},
+		{"m1", "p1", "welcome", "t1", "delivered",
+			`"Ann" <ann@example.com>, bob@example.com`, "cat@example.com", "audit@example.com",
+			"shop@example.com", "help@example.com", "Welcome",
+			"2026-10-01T09:30:00Z", "", "2026-10-01T09:31:00Z", "2026-10-01T09:31:00Z"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV\n%q\nwant\n%q", records, want)
+	}
+}
+
+func TestCSVExportColumnsWithLogs(t *testing.T) {
+	m := testExportMail()
+	empty := testExportMail()
+	empty.ID = "m2"
+	at := m.CreatedAt
+	logs := [][]http.MailLog{{
+		{ID: "l1", Status: http.MailStatusPending, CreatedAt: at},
+		{ID: "l2", Status: http.MailStatusDelivered, SMTPCode: 250, Msg: "250 Ok", CreatedAt: at,
+			Data: map[string]interface{}{"remoteMta": "mx.example.com", "tls": map[string]interface{}{"version": "1.3"}}},
+	}, nil}
+	records := exportCSV(t, true, []http.Mail{m, empty}, logs)
+
+	header := append(append([]string{}, csvMailColumns...),
+		"log_id", "log_status", "log_smtp_code", "log_message", "log_created_at",
+		"data.remoteMta", "data.tls.version", "data_extra")
+	if !reflect.DeepEqual(records[0], header) {
+		t.Fatalf("header %q, want %q", records[0], header)
+	}
+	if len(records) != 4 {
+		t.Fatalf("%d records, want a header and one row per log or mail without logs", len(records))
+	}
+	for _, r := range records[1:] {
+		if len(r) != len(header) {
+			t.Errorf("row %q has %d columns, want %d", r, len(r), len(header))
+		}
+	}
+	n := len(csvMailColumns)
+	if got := records[2][n:]; !reflect.DeepEqual(got,
+		[]string{"l2", "delivered", "250", "250 Ok", "2026-10-01T09:30:00Z", "mx.example.com", "1.3", ""}) {
+		t.Errorf("log columns %q", got)
+	}
+	if records[3][0] != "m2" || records[3][n] != "" {
+		t.Errorf("mail without logs row %q", records[3])
+	}
+}
@@ -1,10 +1,14 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/andyfusniak/raven-client-go/http"
@@ -73,10 +77,10 @@ func NewCmdListMailLogs() *cobra.Command {
 				return err
 			}
 
-			format = "%s\t%s\t\t%v\t%s\t%v\n"
+			format = "%s\t%s\t\t%v\t%s\t%s\t%v\n"
 			headers = []interface{}{
 				"├── MAIL LOG ID", "STATUS",
-				"SMTP CODE", "MSG", "DURATION",
+				"SMTP CODE", "BOUNCE", "MSG", "DURATION",
 			}
 			if err := renderTable(os.Stdout, results, format, headers, mail.CreatedAt); err != nil {
 				return fmt.Errorf("list mail logs failed to render table: %+v", err)
@@ -88,7 +92,9 @@ func NewCmdListMailLogs() *cobra.Command {
 
 // NewCmdGetMail get mail sub command.
 func NewCmdGetMail() *cobra.Command {
-	return &cobra.Command{
+	var raw bool
+	var body bool
+	cmd := &cobra.Command{
 		Use:     "mail MAIL_ID",
 		Short:   "Get a mail entry",
 		Aliases: []string{"mails"},
@@ -103,6 +109,15 @@ func NewCmdGetMail() *cobra.Command {
 			app := ctx.Value(AppKey("app")).(*App)
 
 			mailID := args[0]
+			if raw {
+				content, err := getMailContent(ctx, app, mailID)
+				if err != nil {
+					return err
+				}
+				_, err = io.WriteString(os.Stdout, content.Raw)
+				return err
+			}
+
 			result, err := app.HTTPClient.GetMail(ctx, app.projectID, mailID)
 			if err != nil {
 				if terr, ok := err.(*http.APIError); ok {
@@ -120,9 +135,397 @@ func NewCmdGetMail() *cobra.Command {
 				return err
 			}
 
+			if body {
+				content, err := getMailContent(ctx, app, mailID)
+				if err != nil {
+					return err
+				}
+				if err := renderMailContent(os.Stdout, content); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&raw, "raw", false, "print the raw RFC 5322 message source as sent")
+	cmd.Flags().BoolVar(&body, "body", false, "include the rendered text and HTML bodies")
+	return cmd
+}
+
+func getMailContent(ctx context.Context, app *App, mailID string) (*http.MailContent, error) {
+	content, err := app.HTTPClient.GetMailContent(ctx, app.projectID, mailID)
+	if err != nil {
+		if terr, ok := err.(*http.APIError); ok {
+			if terr.Code == http.ErrCodeMailNotFound {
+				fmt.Fprintf(os.Stderr,
+					"Mail %q not found - use raven list mail for a full list.\n",
+					mailID)
+				os.Exit(1)
+			}
+		}
+		return nil, err
+	}
+	return content, nil
+}
+
+// NewCmdCancelMail cancel mail sub command.
+func NewCmdCancelMail() *cobra.Command {
+	return &cobra.Command{
+		Use:     "mail MAIL_ID",
+		Short:   "Cancel a pending or scheduled mail entry",
+		Aliases: []string{"mails"},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing MAIL_ID argument")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			mailID := args[0]
+			result, err := app.HTTPClient.CancelMail(ctx, app.projectID, mailID)
+			if err != nil {
+				if terr, ok := err.(*http.APIError); ok {
+					if terr.Code == http.ErrCodeMailNotFound {
+						fmt.Fprintf(os.Stderr,
+							"Mail %q not found - use raven list mail for a full list.\n",
+							mailID)
+						os.Exit(1)
+					}
+					if terr.Code == http.ErrCodeMailNotCancellable {
+						fmt.Fprintf(os.Stderr,
+							"Mail %q can no longer be cancelled: %s\n", mailID, terr.Message)
+						os.Exit(1)
+					}
+				}
+				return err
+			}
+
+			return renderMail(os.Stdout, result)
+		},
+	}
+}
+
+// NewCmdResendMail resend mail sub command.
+func NewCmdResendMail() *cobra.Command {
+	var transportID string
+	cmd := &cobra.Command{
+		Use:     "mail MAIL_ID...",
+		Short:   "Resend one or more mail entries",
+		Aliases: []string{"mails"},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("must contain at least one MAIL_ID")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			failed := 0
+			for _, mailID := range args {
+				result, err := app.HTTPClient.ResendMail(ctx, app.projectID, mailID, http.ResendOptions{
+					TransportID: transportID,
+				})
+				if err != nil {
+					failed++
+					if terr, ok := err.(*http.APIError); ok {
+						switch terr.Code {
+						case http.ErrCodeMailNotFound:
+							fmt.Fprintf(os.Stderr, "%s %s: mail not found\n", crossMark, mailID)
+							continue
+						case http.ErrCodeTransportNotFound:
+							fmt.Fprintf(os.Stderr,
+								"transport %q not found - use raven list transports for a full list.\n",
+								transportID)
+							os.Exit(1)
+						}
+					}
+					fmt.Fprintf(os.Stderr, "%s %s: %v\n", crossMark, mailID, err)
+					continue
+				}
+				fmt.Fprintf(os.Stdout, "%s %s %s\n", checkMark, result.ID, renderMailStatus(result.Status))
+			}
+			if failed > 0 {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&transportID, "transport", "", "resend using this transport instead of the active one")
+	return cmd
+}
+
+// NewCmdRetry retry sub command.
+func NewCmdRetry() *cobra.Command {
+	var status string
+	var since time.Duration
+	var templateID string
+	var transportID string
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:     "retry",
+		Short:   "Resend all mail matching a status",
+		Example: "  raven retry --status failed --since 1h --dry-run",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			s, err := http.ParseMailStatus(status)
+			if err != nil {
+				return fmt.Errorf("--status: %w", err)
+			}
+			filter := http.RetryFilter{
+				Status:      s,
+				TemplateID:  templateID,
+				TransportID: transportID,
+				DryRun:      dryRun,
+			}
+			if since > 0 {
+				filter.Since = time.Now().Add(-since)
+			}
+
+			results, err := app.HTTPClient.RetryFailed(ctx, app.projectID, filter)
+			if err != nil {
+				return err
+			}
+
+			failed := 0
+			for _, r := range results {
+				switch {
+				case dryRun:
+					fmt.Fprintf(os.Stdout, "would resend %s\t%s\t%s\t%s\n",
+						r.Mail.ID, r.Mail.TemplateID, renderMailTo(r.Mail), r.Mail.CreatedAt)
+				case r.Err != nil:
+					failed++
+					fmt.Fprintf(os.Stderr, "%s %s: %v\n", crossMark, r.Mail.ID, r.Err)
+				default:
+					fmt.Fprintf(os.Stdout, "%s %s %s\n", checkMark, r.Resent.ID, renderMailStatus(r.Resent.Status))
+				}
+			}
+
+			verb := "resent"
+			if dryRun {
+				verb = "matched"
+			}
+			fmt.Fprintf(os.Stdout, "%d mail %s, %d failed\n", len(results)-failed, verb, failed)
+			if failed > 0 {
+				os.Exit(1)
+			}
 			return nil
 		},
 	}
+	cmd.Flags().StringVar(&status, "status", "failed", "status of mail to resend")
+	cmd.Flags().DurationVar(&since, "since", 0, "only resend mail created within this duration, e.g. 1h")
+	cmd.Flags().StringVar(&templateID, "template", "", "only resend mail sent with this template")
+	cmd.Flags().StringVar(&transportID, "transport", "", "resend using this transport instead of the active one")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "list the mail that would be resent without resending it")
+	return cmd
+}
+
+// Exit codes used by raven wait mail.
+const (
+	exitWaitError    = 1
+	exitWaitUnwanted = 2
+	exitWaitTimeout  = 3
+)
+
+// NewCmdWaitMail wait mail sub command.
+func NewCmdWaitMail() *cobra.Command {
+	var statuses []string
+	var timeout time.Duration
+	cmd := &cobra.Command{
+		Use:     "mail MAIL_ID",
+		Short:   "Wait for a mail entry to reach a status",
+		Aliases: []string{"mails"},
+		Long: `Wait for a mail entry to reach a status, printing each new mail log as it
+arrives.
+
+Exit codes:
+  0  the mail reached one of the --for statuses
+  1  an error occurred
+  2  the mail reached a different terminal status (e.g. failed)
+  3  the timeout expired`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing MAIL_ID argument")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			mailID := args[0]
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+
+			var wantStatuses []http.MailStatus
+			for _, s := range statuses {
+				v, err := http.ParseMailStatus(s)
+				if err != nil {
+					return fmt.Errorf("--for: %w", err)
+				}
+				wantStatuses = append(wantStatuses, v)
+			}
+
+			// log times are shown relative to the mail's creation, as in
+			// raven list mail-logs
+			mail, err := app.HTTPClient.GetMail(ctx, app.projectID, mailID)
+			if err == nil {
+				created := mail.CreatedAt
+				logs := make(chan http.MailLog)
+				done := make(chan struct{})
+				go func() {
+					defer close(done)
+					mail, err = app.HTTPClient.WaitForMail(ctx, app.projectID, mailID, http.WaitOptions{
+						For:  wantStatuses,
+						Logs: logs,
+					})
+				}()
+
+				for l := range logs {
+					fmt.Fprintf(os.Stdout, "%s\t%s\t%d\t%s\t%s\n",
+						l.ID, renderMailStatus(l.Status), l.SMTPCode, l.Msg,
+						renderRelativeTime(created, l.CreatedAt))
+				}
+				<-done
+			}
+
+			var werr *http.WaitError
+			switch {
+			case err == nil:
+				fmt.Fprintf(os.Stdout, "mail %s is %s\n", mailID, mail.Status)
+				return nil
+			case errors.As(err, &werr):
+				fmt.Fprintf(os.Stderr, "mail %s is %s, wanted %s\n",
+					mailID, werr.Mail.Status, strings.Join(statuses, " or "))
+				os.Exit(exitWaitUnwanted)
+			case errors.Is(err, context.DeadlineExceeded):
+				status := http.MailStatus("unknown")
+				if mail != nil {
+					status = mail.Status
+				}
+				fmt.Fprintf(os.Stderr, "timed out after %s waiting for mail %s (status %s)\n",
+					timeout, mailID, status)
+				os.Exit(exitWaitTimeout)
+			}
+			var terr *http.APIError
+			if errors.As(err, &terr) && terr.Code == http.ErrCodeMailNotFound {
+				fmt.Fprintf(os.Stderr,
+					"Mail %q not found - use raven list mail for a full list.\n",
+					mailID)
+				os.Exit(exitWaitError)
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringSliceVar(&statuses, "for", []string{"delivered"}, "comma separated statuses to wait for")
+	cmd.Flags().DurationVar(&timeout, "timeout", 2*time.Minute, "maximum time to wait (0 waits indefinitely)")
+	return cmd
+}
+
+// NewCmdLogs logs sub command.
+func NewCmdLogs() *cobra.Command {
+	var follow bool
+	var poll bool
+	var since time.Duration
+	cmd := &cobra.Command{
+		Use:   "logs [MAIL_ID]",
+		Short: "Show mail logs for a mail entry or the whole project",
+		Long: `Show mail logs for a single mail entry, or for every mail entry in the
+project if MAIL_ID is omitted. Use -f to keep streaming new logs as they
+arrive until interrupted.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			var mailID string
+			if len(args) > 0 {
+				mailID = args[0]
+			}
+			var from time.Time
+			if since > 0 {
+				from = time.Now().Add(-since)
+			}
+
+			if !follow {
+				var results []http.MailLog
+				var err error
+				if mailID != "" {
+					results, err = app.HTTPClient.ListMailLogs(ctx, app.projectID, mailID)
+				} else {
+					results, err = app.HTTPClient.ListProjectMailLogs(ctx, app.projectID, from)
+				}
+				if err != nil {
+					return err
+				}
+				for _, l := range results {
+					if l.CreatedAt.After(from) {
+						renderMailLogLine(os.Stdout, l)
+					}
+				}
+				return nil
+			}
+
+			logs := make(chan http.MailLog)
+			errc := make(chan error, 1)
+			go func() {
+				errc <- app.HTTPClient.FollowMailLogs(ctx, app.projectID, http.FollowOptions{
+					MailID:        mailID,
+					Since:         from,
+					DisableStream: poll,
+					Logs:          logs,
+				})
+			}()
+			for l := range logs {
+				renderMailLogLine(os.Stdout, l)
+			}
+			if err := <-errc; err != nil && !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "stream new logs as they arrive")
+	cmd.Flags().BoolVar(&poll, "poll", false, "poll for new logs instead of using the event stream")
+	cmd.Flags().DurationVar(&since, "since", 10*time.Minute, "only show logs newer than a relative duration (0 shows all)")
+	return cmd
+}
+
+func renderMailContent(w io.Writer, c *http.MailContent) error {
+	fmt.Fprintf(w, "TEXT:\n%s\n", c.Txt)
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "HTML:\n%s\n", c.HTML)
+	return nil
+}
+
+func renderAddresses(list []http.Address) string {
+	s := make([]string, len(list))
+	for i, a := range list {
+		s[i] = a.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+func renderMailLogLine(w io.Writer, l http.MailLog) {
+	fmt.Fprintf(w, "%s  %-20s  %-20s  %-14s  %3d  %s\n",
+		l.CreatedAt.Local().Format(time.RFC3339),
+		l.MailID,
+		l.ID,
+		renderMailStatus(l.Status),
+		l.SMTPCode,
+		l.Msg)
 }
 
 func renderMail(w io.Writer, m *http.Mail) error {
@@ -130,14 +533,32 @@ func renderMail(w io.Writer, m *http.Mail) error {
 	fmt.Fprintf(w, "TEMPLATE ID:\t%s\n", m.TemplateID)
 	fmt.Fprintf(w, "PROJECT ID:\t%s\n", m.ProjectID)
 	fmt.Fprintf(w, "STATUS:\t\t%s\n", m.Status)
-	fmt.Fprintf(w, "TO:\t\t%s\n", m.EmailTo)
+	fmt.Fprintf(w, "TO:\t\t%s\n", renderAddresses(m.Recipients()))
+	if len(m.Cc) > 0 {
+		fmt.Fprintf(w, "CC:\t\t%s\n", renderAddresses(m.Cc))
+	}
+	if len(m.Bcc) > 0 {
+		fmt.Fprintf(w, "BCC:\t\t%s\n", renderAddresses(m.Bcc))
+	}
 	fmt.Fprintf(w, "FROM:\t\t%s\n", m.EmailFrom)
 	fmt.Fprintf(w, "REPLY TO:\t%s\n", m.EmailReplyTo)
 	fmt.Fprintf(w, "SUBJECT:\t%s\n", m.Subject)
-	// fmt.Fprintf(w, "TEXT: %s\n", m.TxtDigest[:7])
-	// fmt.Fprintf(w, "%s\n", t.Txt)
+	if len(m.Headers) > 0 {
+		names := make([]string, 0, len(m.Headers))
+		for k := range m.Headers {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		fmt.Fprintf(w, "HEADERS:\n")
+		for _, k := range names {
+			fmt.Fprintf(w, "  %s: %s\n", k, m.Headers[k])
+		}
+	}
 
 	fmt.Fprintf(w, "CREATED AT:\t%s\n", m.CreatedAt)
+	if m.SendAt != nil {
+		fmt.Fprintf(w, "SEND AT:\t%s\n", m.SendAt.Format(time.RFC1123))
+	}
 	fmt.Fprintf(w, "SENT AT:\t%s\n", renderMailSentAt(m.SentAt))
 	fmt.Fprintf(w, "LAST MODIFIED:\t%v\n", m.ModifiedAt)
 	fmt.Fprintln(w)
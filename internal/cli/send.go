@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/andyfusniak/raven-client-go/http"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdSend send sub command.
+func NewCmdSend() *cobra.Command {
+	var to, cc, bcc []string
+	var headers []string
+	var subject string
+	var params []string
+	var attach []string
+	var inline []string
+	var at string
+	var idempotencyKey string
+	cmd := &cobra.Command{
+		Use:   "send TEMPLATE_ID",
+		Short: "Send a mail using a template",
+		Example: `  raven send invoice --to jane@example.com --param name=Jane \
+    --attach invoice-1001.pdf --inline logo=logo.png
+
+  raven send newsletter --to list@example.com --at 2026-11-01T09:00Z
+
+  raven send update --to "Jane Doe <jane@example.com>" --cc ops@example.com \
+    --header "List-Unsubscribe: <https://example.com/unsub>"
+
+  raven send welcome --to jane@example.com --idempotency-key welcome-user-42`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing TEMPLATE_ID argument")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			if len(to) == 0 {
+				return errors.New("--to is required")
+			}
+			toList, err := parseAddressFlags("to", to)
+			if err != nil {
+				return err
+			}
+			ccList, err := parseAddressFlags("cc", cc)
+			if err != nil {
+				return err
+			}
+			bccList, err := parseAddressFlags("bcc", bcc)
+			if err != nil {
+				return err
+			}
+			customHeaders, err := parseHeaderFlags(headers)
+			if err != nil {
+				return err
+			}
+
+			templateParams, err := parseKeyValues(params)
+			if err != nil {
+				return fmt.Errorf("--param: %w", err)
+			}
+
+			p := &http.SendMailParams{
+				ProjectID:  app.projectID,
+				TemplateID: args[0],
+				To:         toList,
+				Cc:         ccList,
+				Bcc:        bccList,
+				Headers:    customHeaders,
+				Subject:    subject,
+				Params:     templateParams,
+
+				IdempotencyKey: idempotencyKey,
+			}
+			if at != "" {
+				sendAt, err := parseSendAt(at, time.Now())
+				if err != nil {
+					return err
+				}
+				p.SendAt = sendAt
+			}
+			for _, filename := range attach {
+				a, err := readAttachment(filename, "")
+				if err != nil {
+					return err
+				}
+				p.Attachments = append(p.Attachments, *a)
+			}
+			for _, v := range inline {
+				cid, filename, ok := strings.Cut(v, "=")
+				if !ok || cid == "" || filename == "" {
+					return fmt.Errorf("--inline %q must be in the form cid=FILE", v)
+				}
+				a, err := readAttachment(filename, cid)
+				if err != nil {
+					return err
+				}
+				p.Inline = append(p.Inline, *a)
+			}
+
+			result, err := app.HTTPClient.SendMail(ctx, p)
+			if err != nil {
+				var aerr *http.AttachmentError
+				if errors.As(err, &aerr) {
+					fmt.Fprintf(os.Stderr, "%s\n", aerr)
+					os.Exit(1)
+				}
+				var verr *http.ValidationError
+				if errors.As(err, &verr) {
+					fmt.Fprintf(os.Stderr, "%s\n", verr)
+					os.Exit(1)
+				}
+				if terr, ok := err.(*http.APIError); ok {
+					if terr.Code == http.ErrCodeTemplateNotFound {
+						fmt.Fprintf(os.Stderr,
+							"Template %q not found - use raven list templates for a full list.\n",
+							args[0])
+						os.Exit(1)
+					}
+				}
+				return err
+			}
+
+			return renderMail(os.Stdout, result)
+		},
+	}
+	cmd.Flags().StringArrayVar(&to, "to", nil, `recipient address(es), e.g. "Jane Doe <jane@example.com>" (repeatable)`)
+	cmd.Flags().StringArrayVar(&cc, "cc", nil, "carbon copy address(es) (repeatable)")
+	cmd.Flags().StringArrayVar(&bcc, "bcc", nil, "blind carbon copy address(es) (repeatable)")
+	cmd.Flags().StringArrayVar(&headers, "header", nil, `custom header as "Name: value", e.g. "X-Campaign: spring" (repeatable)`)
+	cmd.Flags().StringVar(&subject, "subject", "", "subject line (overrides the template)")
+	cmd.Flags().StringArrayVar(&params, "param", nil, "template parameter as key=value (repeatable)")
+	cmd.Flags().StringArrayVar(&attach, "attach", nil, "attach FILE (repeatable)")
+	cmd.Flags().StringVar(&at, "at", "", "schedule delivery at a future time, e.g. 2026-11-01T09:00Z")
+	cmd.Flags().StringArrayVar(&inline, "inline", nil, "inline image as cid=FILE referenced by cid:<cid> in HTML (repeatable)")
+	cmd.Flags().StringVar(&idempotencyKey, "idempotency-key", "", "send at most once per KEY, making re-runs of a script safe")
+	return cmd
+}
+
+// sendAtLayouts accepted by --at. Times without a zone are local.
+var sendAtLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+func parseSendAt(s string, now time.Time) (time.Time, error) {
+	for _, layout := range sendAtLayouts {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err != nil {
+			continue
+		}
+		if !t.After(now) {
+			return time.Time{}, fmt.Errorf("--at %s is in the past", s)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("--at %q is not a valid time, use e.g. 2026-11-01T09:00Z", s)
+}
+
+func readAttachment(filename, cid string) (*http.Attachment, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return nil, fmt.Errorf("attachment %s: %w", filename, err)
+	}
+	if fi.Size() > http.MaxAttachmentSize {
+		return nil, &http.AttachmentError{
+			Filename: filename,
+			Size:     fi.Size(),
+			Limit:    http.MaxAttachmentSize,
+		}
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("attachment %s: %w", filename, err)
+	}
+	return &http.Attachment{
+		Filename:  filename,
+		Content:   b,
+		ContentID: cid,
+	}, nil
+}
+
+// parseAddressFlags parses each flag value as a comma separated RFC 5322
+// address list.
+func parseAddressFlags(flag string, values []string) ([]http.Address, error) {
+	var out []http.Address
+	for _, v := range values {
+		list, err := http.ParseAddressList(v)
+		if err != nil {
+			return nil, fmt.Errorf("--%s: %w", flag, err)
+		}
+		out = append(out, list...)
+	}
+	return out, nil
+}
+
+func parseHeaderFlags(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]string, len(values))
+	for _, v := range values {
+		name, value, ok := strings.Cut(v, ":")
+		if !ok {
+			return nil, fmt.Errorf("--header %q must be in the form \"Name: value\"", v)
+		}
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if err := http.ValidateHeader(name, value); err != nil {
+			return nil, fmt.Errorf("--header: %w", err)
+		}
+		m[name] = value
+	}
+	return m, nil
+}
+
+func parseKeyValues(pairs []string) (http.TemplateActions, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	m := make(http.TemplateActions, len(pairs))
+	for _, p := range pairs {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("%q must be in the form key=value", p)
+		}
+		m[k] = v
+	}
+	return m, nil
+}
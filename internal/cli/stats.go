@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/andyfusniak/raven-client-go/http"
+	"github.com/spf13/cobra"
+)
+
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// NewCmdStats stats sub command.
+func NewCmdStats() *cobra.Command {
+	var since string
+	var bucket string
+	var by string
+	var clientSide bool
+	cmd := &cobra.Command{
+		Use:     "stats",
+		Short:   "Show delivery statistics",
+		Example: "  raven stats --since 7d --by template",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			d, err := parseDays(since)
+			if err != nil {
+				return fmt.Errorf("--since: %w", err)
+			}
+			params := http.StatsParams{
+				Until:      time.Now(),
+				GroupBy:    by,
+				ClientSide: clientSide,
+			}
+			params.Since = params.Until.Add(-d)
+			if bucket != "" {
+				if params.Bucket, err = parseDays(bucket); err != nil {
+					return fmt.Errorf("--bucket: %w", err)
+				}
+			}
+
+			stats, err := app.HTTPClient.GetStats(ctx, app.projectID, params)
+			if err != nil {
+				return err
+			}
+			return renderStats(os.Stdout, stats)
+		},
+	}
+	cmd.Flags().StringVar(&since, "since", "7d", "reporting period ending now, e.g. 12h, 7d or 4w")
+	cmd.Flags().StringVar(&bucket, "bucket", "", "width of each sparkline bucket, e.g. 1h or 1d")
+	cmd.Flags().StringVar(&by, "by", http.StatsByStatus, "group by status, template or transport")
+	cmd.Flags().BoolVar(&clientSide, "client-side", false, "aggregate mail locally instead of using the stats endpoint")
+	return cmd
+}
+
+// parseDays parses a duration, additionally accepting d (day) and w (week)
+// suffixes.
+func parseDays(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n := strings.TrimSuffix(s, suffix); n != s {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil || v <= 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(v * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+func renderStats(w io.Writer, s *http.Stats) error {
+	source := "server"
+	if s.ClientSide {
+		source = "client-side"
+	}
+	fmt.Fprintf(w, "PERIOD:\t\t%s to %s (%s buckets, %s)\n",
+		s.Since.Format(time.RFC1123), s.Until.Format(time.RFC1123), s.Bucket, source)
+	fmt.Fprintf(w, "TOTAL:\t\t%d\n", s.Total)
+
+	statuses := make([]http.MailStatus, 0, len(s.ByStatus))
+	for k := range s.ByStatus {
+		statuses = append(statuses, k)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i] < statuses[j] })
+	for _, k := range statuses {
+		fmt.Fprintf(w, "  %-14s\t%d\n", renderMailStatus(k), s.ByStatus[k])
+	}
+
+	if s.Delivery.Count > 0 {
+		fmt.Fprintf(w, "TIME TO DELIVERED (n=%d):\tp50 %s\tp90 %s\tp99 %s\tmax %s\n",
+			s.Delivery.Count,
+			s.Delivery.P50.Round(time.Millisecond),
+			s.Delivery.P90.Round(time.Millisecond),
+			s.Delivery.P99.Round(time.Millisecond),
+			s.Delivery.Max.Round(time.Millisecond))
+	}
+	fmt.Fprintln(w)
+
+	tw := new(tabwriter.Writer).Init(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\t%s\n", strings.ToUpper(s.GroupBy), "TOTAL", "TREND")
+	for _, g := range s.Groups {
+		key := g.Key
+		if key == "" {
+			key = "-"
+		}
+		if s.GroupBy == http.StatsByStatus {
+			key = renderMailStatus(http.MailStatus(g.Key))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", key, g.Total, renderSparkline(g.Buckets))
+	}
+	return tw.Flush()
+}
+
+// renderSparkline scales counts to block characters relative to the
+// largest bucket.
+func renderSparkline(counts []int) string {
+	max := 0
+	for _, c := range counts {
+		if c > max {
+			max = c
+		}
+	}
+	var b strings.Builder
+	for _, c := range counts {
+		if max == 0 || c == 0 {
+			b.WriteRune(' ')
+			continue
+		}
+		i := (c*len(sparkTicks) - 1) / max
+		b.WriteRune(sparkTicks[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/andyfusniak/raven-client-go/http"
+	"github.com/andyfusniak/raven-client-go/webhook"
+	"github.com/spf13/cobra"
+)
+
+// NewCmdWebhook webhook sub command.
+func NewCmdWebhook() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "webhook",
+		Short:   "Manage webhook endpoints",
+		Aliases: []string{"webhooks"},
+	}
+	cmd.AddCommand(NewCmdWebhookTest())
+	cmd.AddCommand(NewCmdWebhookRotateSecret())
+	return cmd
+}
+
+// NewCmdCreateWebhook create webhook sub command.
+func NewCmdCreateWebhook() *cobra.Command {
+	var events []string
+	cmd := &cobra.Command{
+		Use:     "webhook URL",
+		Short:   "Create a webhook endpoint",
+		Aliases: []string{"webhooks"},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing URL argument")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			u, err := url.Parse(args[0])
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("invalid webhook URL %q", args[0])
+			}
+			if err := validateEventTypes(events); err != nil {
+				return err
+			}
+
+			result, err := app.HTTPClient.CreateWebhook(ctx, &http.CreateWebhookParams{
+				ProjectID: app.projectID,
+				URL:       u.String(),
+				Events:    events,
+			})
+			if err != nil {
+				if terr, ok := err.(*http.APIError); ok {
+					if terr.Code == http.ErrCodeWebhookURLInvalid {
+						fmt.Fprintf(os.Stderr, "webhook URL %q was rejected: %s\n", args[0], terr.Message)
+						os.Exit(1)
+					}
+				}
+				return err
+			}
+
+			if err := renderWebhook(os.Stdout, result); err != nil {
+				return err
+			}
+			fmt.Fprintln(os.Stdout)
+			fmt.Fprintln(os.Stdout, "Store the secret now; it will not be shown again.")
+			return nil
+		},
+	}
+	cmd.Flags().StringSliceVar(&events, "events", eventTypeStrings(webhook.MailStatusEvents),
+		"comma separated event types to deliver ("+strings.Join(eventTypeStrings(webhook.MailStatusEvents), ", ")+")")
+	return cmd
+}
+
+// NewCmdListWebhooks list webhooks sub command.
+func NewCmdListWebhooks() *cobra.Command {
+	return &cobra.Command{
+		Use:     "webhooks",
+		Short:   "List webhook endpoints",
+		Aliases: []string{"webhook"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			results, err := app.HTTPClient.ListWebhooks(ctx, app.projectID)
+			if err != nil {
+				return err
+			}
+
+			format := "%s\t%s\t%s\t%s\t%v\n"
+			headers := []interface{}{"WEBHOOK ID", "URL", "EVENTS", "ACTIVE", "CREATED"}
+			if err := renderTable(os.Stdout, results, format, headers, time.Time{}); err != nil {
+				return fmt.Errorf("list webhooks failed to render table: %+v", err)
+			}
+			return nil
+		},
+	}
+}
+
+// NewCmdDeleteWebhook delete webhook sub command.
+func NewCmdDeleteWebhook() *cobra.Command {
+	return &cobra.Command{
+		Use:     "webhook WEBHOOK_ID",
+		Short:   "Delete a webhook endpoint",
+		Aliases: []string{"webhooks"},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing WEBHOOK_ID argument")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			webhookID := args[0]
+			if err := app.HTTPClient.DeleteWebhook(ctx, app.projectID, webhookID); err != nil {
+				if terr, ok := err.(*http.APIError); ok {
+					if terr.Code == http.ErrCodeWebhookNotFound {
+						fmt.Fprintf(os.Stderr, "webhook %s not found\n", webhookID)
+						os.Exit(1)
+					}
+				}
+				return err
+			}
+			return nil
+		},
+	}
+}
+
+// NewCmdWebhookTest webhook test sub command.
+func NewCmdWebhookTest() *cobra.Command {
+	var event string
+	cmd := &cobra.Command{
+		Use:   "test WEBHOOK_ID",
+		Short: "Send a test event to a webhook endpoint",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing WEBHOOK_ID argument")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			if event != string(webhook.EventPing) {
+				if err := validateEventTypes([]string{event}); err != nil {
+					return err
+				}
+			}
+
+			webhookID := args[0]
+			result, err := app.HTTPClient.SendTestEvent(ctx, app.projectID, webhookID, event)
+			if err != nil {
+				if terr, ok := err.(*http.APIError); ok {
+					if terr.Code == http.ErrCodeWebhookNotFound {
+						fmt.Fprintf(os.Stderr,
+							"webhook %q not found - use raven list webhooks for a full list.\n",
+							webhookID)
+						os.Exit(1)
+					}
+				}
+				return err
+			}
+
+			fmt.Fprintf(os.Stdout, "EVENT ID:\t%s\n", result.EventID)
+			fmt.Fprintf(os.Stdout, "EVENT TYPE:\t%s\n", result.EventType)
+			fmt.Fprintf(os.Stdout, "STATUS CODE:\t%d\n", result.StatusCode)
+			fmt.Fprintf(os.Stdout, "DURATION:\t%s\n", time.Duration(result.DurationMS)*time.Millisecond)
+			if !result.Success {
+				fmt.Fprintf(os.Stdout, "RESULT:\t\t%s %s\n", crossMark, result.Error)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stdout, "RESULT:\t\t%s\n", checkMark)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&event, "event", string(webhook.EventPing), "event type to send")
+	return cmd
+}
+
+// NewCmdWebhookRotateSecret webhook rotate-secret sub command.
+func NewCmdWebhookRotateSecret() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rotate-secret WEBHOOK_ID",
+		Short: "Replace the signing secret of a webhook endpoint",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 1 {
+				return errors.New("missing WEBHOOK_ID argument")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+			app := ctx.Value(AppKey("app")).(*App)
+
+			webhookID := args[0]
+			result, err := app.HTTPClient.RotateWebhookSecret(ctx, app.projectID, webhookID)
+			if err != nil {
+				if terr, ok := err.(*http.APIError); ok {
+					if terr.Code == http.ErrCodeWebhookNotFound {
+						fmt.Fprintf(os.Stderr, "webhook %s not found\n", webhookID)
+						os.Exit(1)
+					}
+				}
+				return err
+			}
+
+			return renderWebhook(os.Stdout, result)
+		},
+	}
+}
+
+func validateEventTypes(events []string) error {
+	if len(events) == 0 {
+		return errors.New("at least one event type is required")
+	}
+	for _, e := range events {
+		if !webhook.EventType(e).IsMailStatus() {
+			return fmt.Errorf("unknown event type %q - must be one of %s",
+				e, strings.Join(eventTypeStrings(webhook.MailStatusEvents), ", "))
+		}
+	}
+	return nil
+}
+
+func eventTypeStrings(events []webhook.EventType) []string {
+	s := make([]string, len(events))
+	for i, e := range events {
+		s[i] = string(e)
+	}
+	return s
+}
+
+func renderWebhook(w io.Writer, h *http.Webhook) error {
+	fmt.Fprintf(w, "WEBHOOK ID:\t%s\n", h.ID)
+	fmt.Fprintf(w, "PROJECT ID:\t%s\n", h.ProjectID)
+	fmt.Fprintf(w, "URL:\t\t%s\n", h.URL)
+	fmt.Fprintf(w, "EVENTS:\t\t%s\n", strings.Join(h.Events, ", "))
+	fmt.Fprintf(w, "ACTIVE:\t\t%s\n", renderTransportActive(h.Active))
+	if h.Secret != "" {
+		fmt.Fprintf(w, "SECRET:\t\t%s\n", h.Secret)
+	}
+	fmt.Fprintf(w, "CREATED:\t%s\n", h.CreatedAt)
+	fmt.Fprintf(w, "LAST MODIFIED:\t%v\n", h.ModifiedAt)
+	return nil
+}
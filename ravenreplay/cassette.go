@@ -0,0 +1,180 @@
+package ravenreplay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Cassette an ordered list of recorded interactions. Cassettes with a .yaml
+// or .yml extension are stored as YAML, others as indented JSON.
+type Cassette struct {
+	Version      int            `json:"version" yaml:"version"`
+	Interactions []*Interaction `json:"interactions" yaml:"interactions"`
+}
+
+// cassetteVersion is written to new cassettes and checked on load.
+const cassetteVersion = 1
+
+// Interaction a single recorded request and its response.
+type Interaction struct {
+	Request    Request       `json:"request" yaml:"request"`
+	Response   Response      `json:"response" yaml:"response"`
+	RecordedAt time.Time     `json:"recordedAt" yaml:"recordedAt"`
+	Duration   time.Duration `json:"duration" yaml:"duration"`
+}
+
+// Request recorded request.
+type Request struct {
+	Method string      `json:"method" yaml:"method"`
+	URL    string      `json:"url" yaml:"url"`
+	Header http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body   Body        `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Response recorded response.
+type Response struct {
+	StatusCode int         `json:"statusCode" yaml:"statusCode"`
+	Status     string      `json:"status" yaml:"status"`
+	Header     http.Header `json:"header,omitempty" yaml:"header,omitempty"`
+	Body       Body        `json:"body,omitempty" yaml:"body,omitempty"`
+}
+
+// Body a request or response body. Bodies that are valid UTF-8 are stored
+// as strings so that cassettes can be read and edited by hand; others are
+// stored base64 encoded.
+type Body []byte
+
+// MarshalJSON implements json.Marshaler.
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+	var enc struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &enc); err != nil {
+		return errors.Wrap(err, "ravenreplay: body must be a string or {\"base64\": ...}")
+	}
+	v, err := base64.StdEncoding.DecodeString(enc.Base64)
+	if err != nil {
+		return errors.Wrap(err, "ravenreplay: decode base64 body")
+	}
+	*b = v
+	return nil
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (b Body) MarshalYAML() (interface{}, error) {
+	if utf8.Valid(b) {
+		return string(b), nil
+	}
+	return map[string]string{"base64": base64.StdEncoding.EncodeToString(b)}, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (b *Body) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*b = Body(value.Value)
+		return nil
+	}
+	var enc struct {
+		Base64 string `yaml:"base64"`
+	}
+	if err := value.Decode(&enc); err != nil {
+		return errors.Wrap(err, "ravenreplay: body must be a string or {base64: ...}")
+	}
+	v, err := base64.StdEncoding.DecodeString(enc.Base64)
+	if err != nil {
+		return errors.Wrap(err, "ravenreplay: decode base64 body")
+	}
+	*b = v
+	return nil
+}
+
+// isYAML reports whether filename is a YAML cassette.
+func isYAML(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+// LoadCassette reads the cassette at filename.
+func LoadCassette(filename string) (*Cassette, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if isYAML(filename) {
+		err = yaml.Unmarshal(b, &c)
+	} else {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "ravenreplay: decode cassette %s", filename)
+	}
+	if c.Version != cassetteVersion {
+		return nil, errors.Errorf("ravenreplay: cassette %s has unsupported version %d", filename, c.Version)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to filename, creating parent directories as
+// needed.
+func (c *Cassette) Save(filename string) error {
+	c.Version = cassetteVersion
+	var b []byte
+	var err error
+	if isYAML(filename) {
+		b, err = yaml.Marshal(c)
+	} else {
+		if b, err = json.MarshalIndent(c, "", "  "); err == nil {
+			b = append(b, '\n')
+		}
+	}
+	if err != nil {
+		return errors.Wrap(err, "ravenreplay: encode cassette")
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, b, 0644)
+}
+
+// response builds an *http.Response for req from the recorded response.
+func (r *Response) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        r.Status,
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,76 @@
+package ravenreplay
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// Matcher reports whether the recorded interaction in answers req. body is
+// the request body, which has already been read.
+type Matcher func(req *http.Request, body []byte, in *Interaction) bool
+
+// DefaultMatcher matches on method, path and query.
+var DefaultMatcher = All(MatchMethod, MatchPath, MatchQuery)
+
+// All returns a Matcher that matches only if every one of matchers does.
+func All(matchers ...Matcher) Matcher {
+	return func(req *http.Request, body []byte, in *Interaction) bool {
+		for _, m := range matchers {
+			if !m(req, body, in) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// MatchMethod matches the request method.
+func MatchMethod(req *http.Request, _ []byte, in *Interaction) bool {
+	return req.Method == in.Request.Method
+}
+
+// MatchPath matches the escaped request path, ignoring the scheme and host
+// so that cassettes recorded against one endpoint replay against another.
+func MatchPath(req *http.Request, _ []byte, in *Interaction) bool {
+	u, err := url.Parse(in.Request.URL)
+	if err != nil {
+		return false
+	}
+	return req.URL.EscapedPath() == u.EscapedPath()
+}
+
+// MatchQuery matches the query parameters regardless of their order.
+func MatchQuery(req *http.Request, _ []byte, in *Interaction) bool {
+	u, err := url.Parse(in.Request.URL)
+	if err != nil {
+		return false
+	}
+	return reflect.DeepEqual(normalizeQuery(req.URL.Query()), normalizeQuery(u.Query()))
+}
+
+// MatchBody matches the request body. JSON bodies are compared by value so
+// that key order and whitespace do not matter.
+func MatchBody(req *http.Request, body []byte, in *Interaction) bool {
+	var a, b interface{}
+	if json.Unmarshal(body, &a) == nil && json.Unmarshal(in.Request.Body, &b) == nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return bytes.Equal(body, in.Request.Body)
+}
+
+// MatchHeader returns a Matcher that matches the value of header key.
+func MatchHeader(key string) Matcher {
+	return func(req *http.Request, _ []byte, in *Interaction) bool {
+		return req.Header.Get(key) == in.Request.Header.Get(key)
+	}
+}
+
+func normalizeQuery(q url.Values) url.Values {
+	if len(q) == 0 {
+		return nil
+	}
+	return q
+}
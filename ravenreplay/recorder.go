@@ -0,0 +1,285 @@
+// Package ravenreplay records HTTP interactions with the Raven Mailer API
+// to cassette files and replays them offline, giving deterministic tests of
+// code built on the client without a running backend.
+//
+//	rec, err := ravenreplay.NewRecorder(ravenreplay.Config{
+//		Cassette: "testdata/send-mail.json",
+//		Mode:     ravenreplay.ModeReplayOrRecord,
+//	})
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer rec.Stop()
+//
+//	client, err := raven.NewClientWithOptions(endpoint, raven.WithTransport(rec))
+//
+// Run once against a real backend to record the cassette, then commit it
+// and the test replays it without network access. Cassettes named .yaml or
+// .yml are written as YAML, others as JSON.
+package ravenreplay
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Mode controls whether a Recorder replays, records or both.
+type Mode int
+
+const (
+	// ModeReplay serves every request from the cassette. A request with
+	// no matching interaction fails with a *NoMatchError.
+	ModeReplay Mode = iota
+
+	// ModeRecord sends every request to the real transport and records a
+	// new cassette, replacing any existing file on Stop.
+	ModeRecord
+
+	// ModeReplayOrRecord replays matching interactions and records those
+	// that are missing, appending them to the cassette on Stop.
+	ModeReplayOrRecord
+)
+
+// DefaultRedactHeaders request and response headers whose values are not
+// written to cassettes.
+var DefaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// Config parameters to create a new Recorder.
+type Config struct {
+	// Cassette file name, e.g. testdata/list-mail.json.
+	Cassette string
+
+	// Mode left unset defaults to ModeReplay.
+	Mode Mode
+
+	// Transport used to reach the real API when recording. Left unset
+	// defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// Matcher decides whether a recorded interaction answers a request.
+	// Left unset defaults to DefaultMatcher.
+	Matcher Matcher
+
+	// RedactHeaders request and response headers whose values are
+	// replaced with REDACTED when recording. Left unset defaults to
+	// DefaultRedactHeaders.
+	RedactHeaders []string
+}
+
+// Recorder is an http.RoundTripper that records and replays interactions.
+// Each recorded interaction is replayed at most once, in order, so a
+// sequence of identical requests receives the recorded sequence of
+// responses.
+type Recorder struct {
+	filename  string
+	mode      Mode
+	transport http.RoundTripper
+	matcher   Matcher
+	redact    []string
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+	dirty    bool
+}
+
+// NewRecorder creates a Recorder. In ModeReplay the cassette must exist.
+func NewRecorder(c Config) (*Recorder, error) {
+	if c.Cassette == "" {
+		return nil, errors.New("ravenreplay: cassette file name must be set")
+	}
+	r := &Recorder{
+		filename:  c.Cassette,
+		mode:      c.Mode,
+		transport: c.Transport,
+		matcher:   c.Matcher,
+		redact:    c.RedactHeaders,
+		cassette:  &Cassette{Version: cassetteVersion},
+	}
+	if r.transport == nil {
+		r.transport = http.DefaultTransport
+	}
+	if r.matcher == nil {
+		r.matcher = DefaultMatcher
+	}
+	if r.redact == nil {
+		r.redact = DefaultRedactHeaders
+	}
+
+	switch c.Mode {
+	case ModeRecord:
+	case ModeReplay, ModeReplayOrRecord:
+		cas, err := LoadCassette(c.Cassette)
+		switch {
+		case err == nil:
+			r.cassette = cas
+		case os.IsNotExist(errors.Cause(err)) && c.Mode == ModeReplayOrRecord:
+		default:
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("ravenreplay: unknown mode %d", c.Mode)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// NoMatchError is returned in ModeReplay when no unused interaction matches
+// a request.
+type NoMatchError struct {
+	Method string
+	URL    string
+}
+
+// Error string representation of a NoMatchError.
+func (e *NoMatchError) Error() string {
+	return fmt.Sprintf("ravenreplay: no recorded interaction for %s %s", e.Method, e.URL)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, out, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode != ModeRecord {
+		r.mu.Lock()
+		for i, in := range r.cassette.Interactions {
+			if !r.used[i] && r.matcher(req, body, in) {
+				r.used[i] = true
+				r.mu.Unlock()
+				closeBody(out)
+				return in.Response.response(req), nil
+			}
+		}
+		r.mu.Unlock()
+		if r.mode == ModeReplay {
+			closeBody(out)
+			return nil, &NoMatchError{Method: req.Method, URL: req.URL.String()}
+		}
+	}
+
+	start := time.Now()
+	res, err := r.transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	resBody, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "ravenreplay: read response body")
+	}
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	in := &Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    req.URL.String(),
+			Header: r.redactHeader(req.Header),
+			Body:   body,
+		},
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Header:     r.redactHeader(res.Header),
+			Body:       resBody,
+		},
+		RecordedAt: start.UTC(),
+		Duration:   time.Since(start),
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	r.used = append(r.used, true)
+	r.dirty = true
+	r.mu.Unlock()
+	return res, nil
+}
+
+// Stop saves newly recorded interactions to the cassette file. It does
+// nothing if no interactions were recorded.
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirty {
+		return nil
+	}
+	if err := r.cassette.Save(r.filename); err != nil {
+		return err
+	}
+	r.dirty = false
+	return nil
+}
+
+// Unused returns the recorded interactions that have not been replayed,
+// useful for asserting that a test made every expected request.
+func (r *Recorder) Unused() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []*Interaction
+	for i, in := range r.cassette.Interactions {
+		if !r.used[i] {
+			list = append(list, in)
+		}
+	}
+	return list
+}
+
+func (r *Recorder) redactHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, k := range r.redact {
+		if _, ok := out[http.CanonicalHeaderKey(k)]; ok {
+			out.Set(k, "REDACTED")
+		}
+	}
+	return out
+}
+
+// readBody returns the request body and the request to send on to the
+// real transport. A RoundTripper must not modify req, so the body is read
+// through GetBody where possible, leaving req.Body for the transport, and
+// otherwise req is copied with a fresh body.
+func readBody(req *http.Request) ([]byte, *http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, req, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			closeBody(req)
+			return nil, nil, errors.Wrap(err, "ravenreplay: get request body")
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			closeBody(req)
+			return nil, nil, errors.Wrap(err, "ravenreplay: read request body")
+		}
+		return b, req, nil
+	}
+
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "ravenreplay: read request body")
+	}
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(b))
+	return b, out, nil
+}
+
+// closeBody closes the body of a request that is not sent on, as a
+// RoundTripper must.
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
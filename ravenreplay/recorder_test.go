@@ -0,0 +1,166 @@
+package ravenreplay
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc123; Path=/")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"data":` + string(b) + `}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	for _, name := range []string{"cassette.json", "cassette.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			srv := newTestServer(t)
+			cassette := filepath.Join(t.TempDir(), name)
+
+			rec, err := NewRecorder(Config{Cassette: cassette, Mode: ModeRecord})
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := []byte(`{"name":"Onboarding"}`)
+			binary := []byte{0xff, 0xfe, 0x00}
+			for _, b := range [][]byte{body, binary} {
+				req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/projects/p1/groups", bytes.NewReader(b))
+				if err != nil {
+					t.Fatal(err)
+				}
+				req.Header.Set("Authorization", "Bearer live-key")
+				res, err := (&http.Client{Transport: rec}).Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res.Body.Close()
+				if res.Header.Get("Set-Cookie") == "" {
+					t.Error("recording removed Set-Cookie from the live response")
+				}
+			}
+			if err := rec.Stop(); err != nil {
+				t.Fatal(err)
+			}
+
+			cas, err := LoadCassette(cassette)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cas.Interactions) != 2 {
+				t.Fatalf("cassette has %d interactions, want 2", len(cas.Interactions))
+			}
+			in := cas.Interactions[0]
+			if got := in.Request.Header.Get("Authorization"); got != "REDACTED" {
+				t.Errorf("recorded Authorization %q", got)
+			}
+			if got := in.Response.Header.Get("Set-Cookie"); got != "REDACTED" {
+				t.Errorf("recorded Set-Cookie %q", got)
+			}
+			if !bytes.Equal(in.Request.Body, body) {
+				t.Errorf("recorded request body %q, want %q", in.Request.Body, body)
+			}
+			if !bytes.Equal(cas.Interactions[1].Request.Body, binary) {
+				t.Errorf("recorded binary body %q, want %q", cas.Interactions[1].Request.Body, binary)
+			}
+
+			// the server is gone so only the cassette can answer
+			srv.Close()
+			rec, err = NewRecorder(Config{
+				Cassette: cassette,
+				Matcher:  All(DefaultMatcher, MatchBody),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			req, _ := http.NewRequest(http.MethodPost, "https://api.example.com/v1/projects/p1/groups",
+				strings.NewReader(`{ "name": "Onboarding" }`))
+			res, err := rec.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := io.ReadAll(res.Body)
+			if res.StatusCode != http.StatusCreated || string(got) != `{"data":{"name":"Onboarding"}}` {
+				t.Errorf("replayed %d %s", res.StatusCode, got)
+			}
+
+			req, _ = http.NewRequest(http.MethodPost, "https://api.example.com/v1/projects/p1/groups",
+				strings.NewReader(`{"name":"Other"}`))
+			if _, err := rec.RoundTrip(req); err == nil {
+				t.Error("replayed a request with a different body")
+			} else if _, ok := err.(*NoMatchError); !ok {
+				t.Errorf("error = %T %v, want *NoMatchError", err, err)
+			}
+			if n := len(rec.Unused()); n != 1 {
+				t.Errorf("%d unused interactions, want 1", n)
+			}
+		})
+	}
+}
+
+// trackingBody records whether it was closed.
+type trackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestRoundTripDoesNotModifyRequest(t *testing.T) {
+	srv := newTestServer(t)
+	rec, err := NewRecorder(Config{Cassette: filepath.Join(t.TempDir(), "c.json"), Mode: ModeRecord})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// with GetBody, as set by http.NewRequest for common body types
+	req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := req.Body
+	res, err := rec.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if req.Body != orig {
+		t.Error("RoundTrip replaced req.Body")
+	}
+
+	// without GetBody the body can only be read once
+	tb := &trackingBody{Reader: strings.NewReader(`{"b":2}`)}
+	req, err = http.NewRequest(http.MethodPost, srv.URL, tb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = rec.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if req.Body != tb {
+		t.Error("RoundTrip replaced req.Body")
+	}
+	if !tb.closed {
+		t.Error("RoundTrip did not close req.Body")
+	}
+	if string(got) != `{"data":{"b":2}}` {
+		t.Errorf("server received a different body: %s", got)
+	}
+}
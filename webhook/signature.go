@@ -0,0 +1,111 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SignatureHeader is the request header carrying the event signature in
+// the form t=<unix timestamp>,v1=<hex encoded HMAC-SHA256>.
+const SignatureHeader = "X-Raven-Signature"
+
+// DefaultTolerance is the maximum age of a signed event before it is
+// rejected as a possible replay.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	// ErrMissingSignature the request has no signature header.
+	ErrMissingSignature = errors.New("webhook: missing signature header")
+
+	// ErrInvalidSignatureHeader the signature header could not be parsed.
+	ErrInvalidSignatureHeader = errors.New("webhook: invalid signature header")
+
+	// ErrSignatureMismatch no signature in the header matches the payload.
+	ErrSignatureMismatch = errors.New("webhook: signature mismatch")
+
+	// ErrTimestampOutOfRange the signed timestamp is outside the tolerance.
+	ErrTimestampOutOfRange = errors.New("webhook: timestamp outside tolerance")
+
+	// ErrMissingSecret no secret was given. An empty HMAC key would let
+	// anyone forge valid signatures.
+	ErrMissingSecret = errors.New("webhook: secret must not be empty")
+)
+
+// Sign computes the signature header value for payload signed with secret
+// at time t. Raven Mailer signs the string "<unix timestamp>.<payload>".
+func Sign(secret string, t time.Time, payload []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, computeMAC(secret, ts, payload))
+}
+
+// VerifySignature checks header against payload and secret, rejecting
+// signatures whose timestamp differs from now by more than tolerance. A
+// header may carry more than one v1 signature while a secret is being
+// rotated; any match is accepted.
+func VerifySignature(secret, header string, payload []byte, tolerance time.Duration, now time.Time) error {
+	if secret == "" {
+		return ErrMissingSecret
+	}
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	var ts string
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrInvalidSignatureHeader
+		}
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	if ts == "" || len(sigs) == 0 {
+		return ErrInvalidSignatureHeader
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return ErrInvalidSignatureHeader
+	}
+	if tolerance > 0 {
+		d := now.Sub(time.Unix(unix, 0))
+		if d < 0 {
+			d = -d
+		}
+		if d > tolerance {
+			return ErrTimestampOutOfRange
+		}
+	}
+
+	expected, _ := hex.DecodeString(computeMAC(secret, ts, payload))
+	for _, s := range sigs {
+		got, err := hex.DecodeString(s)
+		if err != nil {
+			continue
+		}
+		if hmac.Equal(got, expected) {
+			return nil
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+func computeMAC(secret, ts string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
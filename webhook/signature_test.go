@@ -0,0 +1,51 @@
+package webhook_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andyfusniak/raven-client-go/webhook"
+)
+
+func TestVerifySignature(t *testing.T) {
+	const secret = "whsec_test"
+	payload := []byte(`{"id":"ev1","type":"ping"}`)
+	now := time.Unix(1700000000, 0)
+	valid := webhook.Sign(secret, now, payload)
+	_, mac, _ := strings.Cut(valid, ",")
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		now     time.Time
+		want    error
+	}{
+		{"valid", secret, valid, payload, now, nil},
+		{"within tolerance", secret, valid, payload, now.Add(5 * time.Minute), nil},
+		{"clock behind within tolerance", secret, valid, payload, now.Add(-5 * time.Minute), nil},
+		{"too old", secret, valid, payload, now.Add(5*time.Minute + time.Second), webhook.ErrTimestampOutOfRange},
+		{"from the future", secret, valid, payload, now.Add(-6 * time.Minute), webhook.ErrTimestampOutOfRange},
+		{"rotated secret", secret, webhook.Sign("whsec_old", now, payload) + "," + mac, payload, now, nil},
+		{"wrong secret", "whsec_other", valid, payload, now, webhook.ErrSignatureMismatch},
+		{"tampered payload", secret, valid, []byte(`{"id":"ev2","type":"ping"}`), now, webhook.ErrSignatureMismatch},
+		{"tampered timestamp", secret, "t=1700000001," + mac, payload, now, webhook.ErrSignatureMismatch},
+		{"non-hex signature", secret, "t=1700000000,v1=zz", payload, now, webhook.ErrSignatureMismatch},
+		{"empty secret", "", valid, payload, now, webhook.ErrMissingSecret},
+		{"missing header", secret, "", payload, now, webhook.ErrMissingSignature},
+		{"no timestamp", secret, mac, payload, now, webhook.ErrInvalidSignatureHeader},
+		{"no signature", secret, "t=1700000000", payload, now, webhook.ErrInvalidSignatureHeader},
+		{"malformed part", secret, "t=1700000000,garbage", payload, now, webhook.ErrInvalidSignatureHeader},
+		{"non-numeric timestamp", secret, "t=soon," + mac, payload, now, webhook.ErrInvalidSignatureHeader},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := webhook.VerifySignature(tc.secret, tc.header, tc.payload, webhook.DefaultTolerance, tc.now)
+			if err != tc.want {
+				t.Errorf("VerifySignature = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+// Package webhook receives and verifies Raven Mailer event notifications.
+//
+// A Handler is an http.Handler that checks the HMAC signature and
+// timestamp of each request, decodes the event and dispatches it to the
+// callbacks registered for its type.
+//
+//	h, err := webhook.NewHandler(webhook.Config{Secret: os.Getenv("RAVEN_WEBHOOK_SECRET")})
+//	if err != nil {
+//		log.Fatal(err) // RAVEN_WEBHOOK_SECRET is not set
+//	}
+//	h.OnMailStatus(func(ctx context.Context, ev *webhook.Event, log *raven.MailLog) error {
+//		fmt.Printf("mail %s is now %s\n", log.MailID, log.Status)
+//		return nil
+//	})
+//	http.Handle("/raven/events", h)
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	raven "github.com/andyfusniak/raven-client-go/http"
+	"github.com/pkg/errors"
+)
+
+// MaxBodyBytes is the largest event payload the handler will read.
+const MaxBodyBytes = 1 << 20
+
+// EventType identifies the kind of event being delivered.
+type EventType string
+
+const (
+	// EventMailScheduled mail has been accepted for delivery at a later time.
+	EventMailScheduled EventType = "mail.scheduled"
+
+	// EventMailPending mail has been accepted and is waiting to be sent.
+	EventMailPending EventType = "mail.pending"
+
+	// EventMailPublished mail has been published to the send queue.
+	EventMailPublished EventType = "mail.published"
+
+	// EventMailReceived mail has been received by the sending worker.
+	EventMailReceived EventType = "mail.received"
+
+	// EventMailDelivered mail has been accepted by the remote MTA.
+	EventMailDelivered EventType = "mail.delivered"
+
+	// EventMailFailed mail could not be delivered.
+	EventMailFailed EventType = "mail.failed"
+
+	// EventMailCancelled mail was cancelled before it was sent.
+	EventMailCancelled EventType = "mail.cancelled"
+
+	// EventPing test event sent when a webhook endpoint is created or tested.
+	EventPing EventType = "ping"
+)
+
+// MailStatusEvents all event types that carry MailLog data.
+var MailStatusEvents = []EventType{
+	EventMailScheduled,
+	EventMailPending,
+	EventMailPublished,
+	EventMailReceived,
+	EventMailDelivered,
+	EventMailFailed,
+	EventMailCancelled,
+}
+
+// IsMailStatus reports whether events of type t carry MailLog data.
+func (t EventType) IsMailStatus() bool {
+	for _, v := range MailStatusEvents {
+		if t == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Event envelope posted by Raven Mailer.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      EventType       `json:"type"`
+	ProjectID string          `json:"projectId"`
+	CreatedAt time.Time       `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// MailLog decodes the event data of a mail status event.
+func (e *Event) MailLog() (*raven.MailLog, error) {
+	if !e.Type.IsMailStatus() {
+		return nil, errors.Errorf("webhook: event type %q does not carry mail log data", e.Type)
+	}
+	var l raven.MailLog
+	if err := json.Unmarshal(e.Data, &l); err != nil {
+		return nil, errors.Wrapf(err, "json decode %s event data", e.Type)
+	}
+	return &l, nil
+}
+
+// HandlerFunc callback invoked for a verified event. Returning an error
+// responds with 500 Internal Server Error so Raven Mailer retries the
+// delivery. A redelivery that arrives while the event is still being
+// handled is answered with 409 Conflict, so Raven Mailer retries it later,
+// and a redelivery after the event was handled is acknowledged without
+// calling the callbacks again.
+type HandlerFunc func(ctx context.Context, ev *Event) error
+
+// MailStatusFunc callback invoked for a verified mail status event.
+type MailStatusFunc func(ctx context.Context, ev *Event, log *raven.MailLog) error
+
+// Config parameters to configure a new webhook handler.
+type Config struct {
+	// Secret shared with Raven Mailer used to sign events.
+	Secret string
+
+	// Tolerance is the maximum age of an event. Left unset defaults to
+	// DefaultTolerance.
+	Tolerance time.Duration
+
+	// OnError (optional) is called when a request is rejected or a
+	// callback fails.
+	OnError func(r *http.Request, err error)
+
+	// Now (optional) returns the current time, used to check signature
+	// timestamps and expire remembered event ids. Left unset defaults to
+	// time.Now; tests may set it to control the clock.
+	Now func() time.Time
+}
+
+// Handler verifies and dispatches Raven Mailer events.
+type Handler struct {
+	secret    string
+	tolerance time.Duration
+	onError   func(r *http.Request, err error)
+
+	mu       sync.RWMutex
+	handlers map[EventType][]HandlerFunc
+	fallback []HandlerFunc
+
+	seenMu sync.Mutex
+	seen   map[string]*seenEntry // event id -> delivery state
+	order  []seenEvent           // seen in the order accepted, for pruning
+
+	now func() time.Time
+}
+
+// seenEntry state of an accepted event. done is set once its callbacks
+// have all succeeded.
+type seenEntry struct {
+	at   time.Time
+	done bool
+}
+
+type seenEvent struct {
+	id    string
+	entry *seenEntry
+}
+
+// claim results.
+const (
+	eventNew      = iota // first delivery, the caller must dispatch it
+	eventInFlight        // an earlier delivery is still being dispatched
+	eventDone            // an earlier delivery was dispatched successfully
+)
+
+// NewHandler creates a new webhook handler. An empty Secret is rejected
+// with ErrMissingSecret.
+func NewHandler(c Config) (*Handler, error) {
+	if c.Secret == "" {
+		return nil, ErrMissingSecret
+	}
+	if c.Tolerance <= 0 {
+		c.Tolerance = DefaultTolerance
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+	return &Handler{
+		secret:    c.Secret,
+		tolerance: c.Tolerance,
+		onError:   c.OnError,
+		handlers:  make(map[EventType][]HandlerFunc),
+		seen:      make(map[string]*seenEntry),
+		now:       c.Now,
+	}, nil
+}
+
+// On registers fn to be called for events of type t.
+func (h *Handler) On(t EventType, fn HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[t] = append(h.handlers[t], fn)
+}
+
+// OnAny registers fn to be called for every event.
+func (h *Handler) OnAny(fn HandlerFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fallback = append(h.fallback, fn)
+}
+
+// OnMailStatus registers fn to be called for every mail status event with
+// the decoded MailLog.
+func (h *Handler) OnMailStatus(fn MailStatusFunc) {
+	wrapped := func(ctx context.Context, ev *Event) error {
+		l, err := ev.MailLog()
+		if err != nil {
+			return err
+		}
+		return fn(ctx, ev, l)
+	}
+	for _, t := range MailStatusEvents {
+		h.On(t, wrapped)
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ev, err := h.parse(r)
+	if err != nil {
+		h.reportError(r, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	switch h.claim(ev.ID, h.now()) {
+	case eventDone:
+		// acknowledged so that Raven Mailer stops retrying it
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case eventInFlight:
+		// only a completed dispatch is acknowledged, as the first
+		// delivery may yet fail
+		http.Error(w, "event is being handled", http.StatusConflict)
+		return
+	}
+
+	if err := h.dispatch(r.Context(), ev); err != nil {
+		h.forget(ev.ID)
+		h.reportError(r, err)
+		http.Error(w, "event handler failed", http.StatusInternalServerError)
+		return
+	}
+	h.finish(ev.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parse reads, verifies and decodes the event in r.
+func (h *Handler) parse(r *http.Request) (*Event, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "webhook: read body")
+	}
+	if len(body) > MaxBodyBytes {
+		return nil, errors.New("webhook: body too large")
+	}
+
+	now := h.now()
+	sig := r.Header.Get(SignatureHeader)
+	if err := VerifySignature(h.secret, sig, body, h.tolerance, now); err != nil {
+		return nil, err
+	}
+
+	var ev Event
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return nil, errors.Wrap(err, "webhook: json decode event")
+	}
+	if ev.ID == "" || ev.Type == "" {
+		return nil, errors.New("webhook: event missing id or type")
+	}
+	return &ev, nil
+}
+
+func (h *Handler) dispatch(ctx context.Context, ev *Event) error {
+	h.mu.RLock()
+	fns := append(append([]HandlerFunc{}, h.handlers[ev.Type]...), h.fallback...)
+	h.mu.RUnlock()
+
+	for _, fn := range fns {
+		if err := fn(ctx, ev); err != nil {
+			return errors.Wrapf(err, "webhook: handle %s event %s", ev.Type, ev.ID)
+		}
+	}
+	return nil
+}
+
+// claim records id as accepted unless it was already seen within the
+// tolerance window, in which case it reports whether that delivery is
+// still in flight or done. Entries older than twice the tolerance are
+// pruned since their signatures can no longer be verified; as entries are
+// kept in the order accepted only the expired ones are visited. Pruning
+// stops at an event that is still being handled.
+func (h *Handler) claim(id string, now time.Time) int {
+	h.seenMu.Lock()
+	defer h.seenMu.Unlock()
+
+	n := 0
+	for _, e := range h.order {
+		if now.Sub(e.entry.at) <= 2*h.tolerance {
+			break
+		}
+		// the id may have been forgotten and accepted again since
+		if cur := h.seen[e.id]; cur == e.entry {
+			if !cur.done {
+				break
+			}
+			delete(h.seen, e.id)
+		}
+		n++
+	}
+	h.order = h.order[n:]
+
+	if e, ok := h.seen[id]; ok {
+		if e.done {
+			return eventDone
+		}
+		return eventInFlight
+	}
+	e := &seenEntry{at: now}
+	h.seen[id] = e
+	h.order = append(h.order, seenEvent{id: id, entry: e})
+	return eventNew
+}
+
+// finish marks id as handled so that redeliveries are acknowledged.
+func (h *Handler) finish(id string) {
+	h.seenMu.Lock()
+	defer h.seenMu.Unlock()
+	if e, ok := h.seen[id]; ok {
+		e.done = true
+	}
+}
+
+// forget removes id so that a redelivery after a failed callback is
+// accepted.
+func (h *Handler) forget(id string) {
+	h.seenMu.Lock()
+	defer h.seenMu.Unlock()
+	delete(h.seen, id)
+}
+
+func (h *Handler) reportError(r *http.Request, err error) {
+	if h.onError != nil {
+		h.onError(r, err)
+	}
+}
@@ -0,0 +1,229 @@
+package webhook_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	raven "github.com/andyfusniak/raven-client-go/http"
+	"github.com/andyfusniak/raven-client-go/webhook"
+)
+
+const testSecret = "whsec_test"
+
+// clock is a manually advanced time source for webhook.Config.Now.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newHandler(t *testing.T, clk *clock) *webhook.Handler {
+	t.Helper()
+	h, err := webhook.NewHandler(webhook.Config{Secret: testSecret, Now: clk.Now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// deliver posts payload signed at the clock's current time to srv.
+func deliver(t *testing.T, srv *httptest.Server, clk *clock, payload string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, srv.URL, bytes.NewReader([]byte(payload)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set(webhook.SignatureHeader, webhook.Sign(testSecret, clk.Now(), []byte(payload)))
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	return res.StatusCode
+}
+
+const deliveredEvent = `{"id":"ev1","type":"mail.delivered","projectId":"p1",` +
+	`"data":{"id":"l1","mailId":"m1","status":"sent","smtpCode":250}}`
+
+func TestNewHandlerRequiresSecret(t *testing.T) {
+	if _, err := webhook.NewHandler(webhook.Config{}); err != webhook.ErrMissingSecret {
+		t.Errorf("NewHandler error = %v, want ErrMissingSecret", err)
+	}
+}
+
+func TestHandlerDispatch(t *testing.T) {
+	clk := &clock{now: time.Unix(1700000000, 0)}
+	h := newHandler(t, clk)
+	var got []*raven.MailLog
+	var any int
+	h.OnMailStatus(func(ctx context.Context, ev *webhook.Event, l *raven.MailLog) error {
+		got = append(got, l)
+		return nil
+	})
+	h.OnAny(func(ctx context.Context, ev *webhook.Event) error {
+		any++
+		return nil
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	if code := deliver(t, srv, clk, deliveredEvent); code != http.StatusNoContent {
+		t.Fatalf("status %d, want 204", code)
+	}
+	if len(got) != 1 || got[0].MailID != "m1" || got[0].SMTPCode != 250 {
+		t.Errorf("OnMailStatus received %+v", got)
+	}
+	if any != 1 {
+		t.Errorf("OnAny called %d times, want 1", any)
+	}
+
+	if code := deliver(t, srv, clk, `{"id":"ev2","type":"ping"}`); code != http.StatusNoContent {
+		t.Errorf("ping status %d, want 204", code)
+	}
+	if len(got) != 1 || any != 2 {
+		t.Errorf("ping dispatched to OnMailStatus %d, OnAny %d times", len(got)-1, any-1)
+	}
+}
+
+func TestHandlerRejects(t *testing.T) {
+	clk := &clock{now: time.Unix(1700000000, 0)}
+	h := newHandler(t, clk)
+	called := false
+	h.OnAny(func(ctx context.Context, ev *webhook.Event) error {
+		called = true
+		return nil
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	post := func(header, payload string) int {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL, bytes.NewReader([]byte(payload)))
+		if header != "" {
+			req.Header.Set(webhook.SignatureHeader, header)
+		}
+		res, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	old := clk.Now().Add(-10 * time.Minute)
+
+	tests := []struct {
+		name   string
+		header string
+		body   string
+	}{
+		{"unsigned", "", deliveredEvent},
+		{"wrong secret", webhook.Sign("other", clk.Now(), []byte(deliveredEvent)), deliveredEvent},
+		{"replayed old signature", webhook.Sign(testSecret, old, []byte(deliveredEvent)), deliveredEvent},
+		{"invalid json", webhook.Sign(testSecret, clk.Now(), []byte("{")), "{"},
+		{"missing id", webhook.Sign(testSecret, clk.Now(), []byte(`{"type":"ping"}`)), `{"type":"ping"}`},
+	}
+	for _, tc := range tests {
+		if code := post(tc.header, tc.body); code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", tc.name, code)
+		}
+	}
+
+	res, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET status %d, want 405", res.StatusCode)
+	}
+	if called {
+		t.Error("a rejected request was dispatched")
+	}
+}
+
+func TestHandlerDeduplicates(t *testing.T) {
+	clk := &clock{now: time.Unix(1700000000, 0)}
+	h := newHandler(t, clk)
+	calls := 0
+	fail := true
+	h.OnAny(func(ctx context.Context, ev *webhook.Event) error {
+		calls++
+		if fail {
+			return errors.New("database unavailable")
+		}
+		return nil
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	// a failed dispatch is not remembered so the redelivery is handled
+	if code := deliver(t, srv, clk, deliveredEvent); code != http.StatusInternalServerError {
+		t.Fatalf("failing callback status %d, want 500", code)
+	}
+	fail = false
+	if code := deliver(t, srv, clk, deliveredEvent); code != http.StatusNoContent {
+		t.Fatalf("redelivery status %d, want 204", code)
+	}
+	if code := deliver(t, srv, clk, deliveredEvent); code != http.StatusNoContent {
+		t.Fatalf("duplicate status %d, want 204", code)
+	}
+	if calls != 2 {
+		t.Errorf("callback called %d times, want 2", calls)
+	}
+
+	// once its signatures can no longer be verified the id is forgotten
+	clk.Add(2*webhook.DefaultTolerance + time.Second)
+	if code := deliver(t, srv, clk, `{"id":"ev9","type":"ping"}`); code != http.StatusNoContent {
+		t.Fatalf("status %d, want 204", code)
+	}
+	if code := deliver(t, srv, clk, deliveredEvent); code != http.StatusNoContent {
+		t.Fatalf("status %d, want 204", code)
+	}
+	if calls != 4 {
+		t.Errorf("callback called %d times after expiry, want 4", calls)
+	}
+}
+
+func TestHandlerConcurrentRedelivery(t *testing.T) {
+	clk := &clock{now: time.Unix(1700000000, 0)}
+	h := newHandler(t, clk)
+	started := make(chan struct{})
+	release := make(chan error)
+	h.OnAny(func(ctx context.Context, ev *webhook.Event) error {
+		close(started)
+		return <-release
+	})
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	first := make(chan int)
+	go func() {
+		first <- deliver(t, srv, clk, deliveredEvent)
+	}()
+	<-started
+
+	// the first delivery may still fail so the redelivery is not acked
+	if code := deliver(t, srv, clk, deliveredEvent); code != http.StatusConflict {
+		t.Errorf("concurrent redelivery status %d, want 409", code)
+	}
+	release <- errors.New("database unavailable")
+	if code := <-first; code != http.StatusInternalServerError {
+		t.Errorf("first delivery status %d, want 500", code)
+	}
+}